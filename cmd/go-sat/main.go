@@ -1,19 +1,38 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/mitchellh/go-sat"
 	"github.com/mitchellh/go-sat/dimacs"
 )
 
+// Exit codes used by SAT competitions. Any other exit code is an error.
+const (
+	exitUnknown = 0
+	exitError   = 1
+	exitSat     = 10
+	exitUnsat   = 20
+)
+
 func main() {
 	os.Exit(realMain())
 }
 
 func realMain() int {
+	var timeout time.Duration
+	var model bool
+	flag.DurationVar(&timeout, "timeout", 0, "give up after this long (0 means no limit)")
+	flag.BoolVar(&model, "model", true, "print the model when satisfiable")
 	flag.Usage = flagUsage
 	flag.Parse()
 
@@ -21,36 +40,147 @@ func realMain() int {
 	args := flag.Args()
 	if len(args) != 1 {
 		flagUsage()
-		return 1
+		return exitError
 	}
 
 	// Parse the CNF file
+	start := time.Now()
 	f, err := os.Open(args[0])
 	if err != nil {
 		printError(err)
-		return 1
+		return exitError
 	}
 
-	p, err := dimacs.Parse(f)
+	// Compressed files are decompressed as they are read
+	r, err := dimacs.Decompress(f)
+	if err != nil {
+		f.Close()
+		printError(fmt.Errorf("error reading cnf file: %s", err))
+		return exitError
+	}
+
+	// Add the clauses to the solver as they are parsed. Variables larger
+	// than the problem line declares are rejected since the solver
+	// allocates every variable up to the largest one.
+	s := sat.New()
+	parser := &dimacs.Parser{CheckVariables: true}
+	p, err := parser.ParseInto(r, s)
 	f.Close()
 	if err != nil {
 		printError(fmt.Errorf("error parsing cnf file: %s", err))
-		return 1
+		return exitError
+	}
+	parseTime := time.Since(start)
+
+	// Stop solving on an interrupt or after the timeout, in which case
+	// the result is unknown.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
 	// Solve the problem
-	var s sat.Solver
-	s.AddFormula(p.Formula)
-	result := s.Solve()
-	fmt.Printf("SAT: %v\n", result)
-	return 0
+	start = time.Now()
+	result := s.SolveContext(ctx)
+	solveTime := time.Since(start)
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	printStats(out, s.Stats(), parseTime, solveTime)
+	fmt.Fprintf(out, "s %s\n", result)
+	switch result {
+	case sat.ResultSat:
+		if model {
+			vars := s.NumVars()
+			if p.Variables > vars {
+				vars = p.Variables
+			}
+
+			printModel(out, s.Assignments(), vars)
+		}
+
+		return exitSat
+
+	case sat.ResultUnsat:
+		return exitUnsat
+
+	default:
+		return exitUnknown
+	}
+}
+
+// printStats prints the solver statistics as comment lines.
+func printStats(w io.Writer, stats sat.Stats, parseTime, solveTime time.Duration) {
+	fmt.Fprintf(w, "c parse time:         %s\n", parseTime)
+	fmt.Fprintf(w, "c solve time:         %s\n", solveTime)
+	fmt.Fprintf(w, "c decisions:          %d\n", stats.Decisions)
+	fmt.Fprintf(w, "c conflicts:          %d\n", stats.Conflicts)
+	fmt.Fprintf(w, "c propagations:       %d\n", stats.Propagations)
+	fmt.Fprintf(w, "c restarts:           %d\n", stats.Restarts)
+	fmt.Fprintf(w, "c learned clauses:    %d\n", stats.LearnedClauses)
+	fmt.Fprintf(w, "c learned literals:   %d\n", stats.LearnedLiterals)
+	fmt.Fprintf(w, "c minimized literals: %d\n", stats.MinimizedLiterals)
+	fmt.Fprintf(w, "c deleted clauses:    %d\n", stats.DeletedClauses)
+	fmt.Fprintf(w, "c max decision level: %d\n", stats.MaxDecisionLevel)
+}
+
+// printModel prints the value of the variables 1 through vars as "v"
+// lines, ending with 0. Variables that aren't in the model are false.
+func printModel(w io.Writer, model map[int]bool, vars int) {
+	// Competitions limit the length of lines so the values are wrapped
+	const maxLine = 78
+
+	line := []byte("v")
+	for v := 1; v <= vars; v++ {
+		lit := v
+		if !model[v] {
+			lit = -v
+		}
+
+		next := strconv.Itoa(lit)
+		if len(line)+1+len(next) > maxLine {
+			line = append(line, '\n')
+			w.Write(line)
+			line = append(line[:0], 'v')
+		}
+
+		line = append(line, ' ')
+		line = append(line, next...)
+	}
+
+	if len(line)+2 > maxLine {
+		line = append(line, '\n')
+		w.Write(line)
+		line = append(line[:0], 'v')
+	}
+
+	line = append(line, " 0\n"...)
+	w.Write(line)
 }
 
 func flagUsage() {
-	fmt.Fprintf(os.Stderr, "Usage: %[1]s [options] <cnf-file>\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Usage: %[1]s [options] <cnf-file>\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "The file may be compressed with gzip, bzip2 or xz.\n\n")
+	fmt.Fprintf(os.Stderr, "The result is printed in the SAT competition format and the\n")
+	fmt.Fprintf(os.Stderr, "exit code is 10 if satisfiable, 20 if unsatisfiable, 0 if\n")
+	fmt.Fprintf(os.Stderr, "unknown and 1 on error.\n\n")
 	flag.PrintDefaults()
 }
 
 func printError(err error) {
-	fmt.Fprintf(os.Stderr, err.Error()+"\n")
+	fmt.Fprintln(os.Stderr, err.Error())
 }
@@ -0,0 +1,401 @@
+// Package count counts the satisfying assignments of a formula in CNF
+// (#SAT, model counting).
+//
+// The count is exact and is computed without enumerating the solutions, so
+// formulas with far too many solutions to enumerate can still be counted.
+// The counter is a DPLL-style search that splits the formula into
+// independent components whenever possible and counts each component
+// separately. The count of every component is cached since the same
+// component often shows up in many parts of the search.
+//
+// Model counting is much harder than deciding satisfiability, so this is
+// only practical for formulas of moderate size or with a lot of structure.
+package count
+
+import (
+	"math/big"
+	"sort"
+	"strconv"
+
+	"github.com/mitchellh/go-sat"
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+// Count returns the number of assignments to the variables 1 through N,
+// where N is the largest variable in f, that satisfy f. Variables that
+// don't appear in f can have either value.
+func Count(f cnf.Formula) *big.Int {
+	n := 0
+	for _, c := range f {
+		for _, l := range c {
+			if v := l.Var(); v > n {
+				n = v
+			}
+		}
+	}
+
+	vars := make([]int, n)
+	for i := range vars {
+		vars[i] = i + 1
+	}
+
+	return CountProjected(f, vars)
+}
+
+// CountProjected returns the number of assignments to the variables vars
+// that can be extended to an assignment that satisfies f. Two satisfying
+// assignments that only differ in variables that aren't in vars are only
+// counted once.
+//
+// vars may contain variables that don't appear in f, which can have either
+// value.
+func CountProjected(f cnf.Formula, vars []int) *big.Int {
+	c := &counter{cache: make(map[string]*big.Int)}
+	for _, v := range vars {
+		c.grow(v)
+		c.counted[v] = true
+	}
+
+	// Copy the clauses, sorting the literals and removing duplicate
+	// literals and tautologies. The clauses are never modified after this
+	// so they can be shared as the formula is simplified.
+	clauses := make([][]cnf.Lit, 0, len(f))
+CLAUSES:
+	for _, clause := range f {
+		lits := make([]cnf.Lit, len(clause))
+		copy(lits, clause)
+		sort.Slice(lits, func(i, j int) bool { return lits[i] < lits[j] })
+
+		j := 0
+		for i, l := range lits {
+			if i > 0 && l == lits[i-1] {
+				continue
+			}
+			if i > 0 && l == lits[i-1].Neg() {
+				continue CLAUSES
+			}
+
+			c.grow(l.Var())
+			lits[j] = l
+			j++
+		}
+
+		clauses = append(clauses, lits[:j])
+	}
+
+	// Counted variables that aren't in the formula can have either value
+	free := c.numCounted(vars) - c.countedIn(clauses)
+	return new(big.Int).Lsh(c.count(clauses), uint(free))
+}
+
+// counter holds the state for counting a single formula.
+type counter struct {
+	counted []bool              // variables that are counted, indexed by var
+	cache   map[string]*big.Int // count for each component
+
+	// Scratch space, indexed by var or literal. These are always reset
+	// to zero after use.
+	marks     []bool // marks for counting vars
+	values    []int8 // assignment during propagation, by literal
+	parent    []int  // union-find parent for components
+	component []int  // component index for union-find roots
+	occurs    []int  // occurrences for choosing the branch var
+}
+
+// grow makes sure the variable v can be used with the counter.
+func (c *counter) grow(v int) {
+	for len(c.counted) <= v {
+		c.counted = append(c.counted, false)
+		c.marks = append(c.marks, false)
+		c.values = append(c.values, 0, 0)
+		c.parent = append(c.parent, 0)
+		c.component = append(c.component, 0)
+		c.occurs = append(c.occurs, 0)
+	}
+}
+
+// numCounted returns the number of distinct counted variables in vars.
+func (c *counter) numCounted(vars []int) int {
+	result := 0
+	for _, v := range vars {
+		if c.counted[v] && !c.marks[v] {
+			c.marks[v] = true
+			result++
+		}
+	}
+	for _, v := range vars {
+		c.marks[v] = false
+	}
+
+	return result
+}
+
+// countedIn returns the number of distinct counted variables in clauses.
+func (c *counter) countedIn(clauses [][]cnf.Lit) int {
+	var vars []int
+	for _, clause := range clauses {
+		for _, l := range clause {
+			vars = append(vars, l.Var())
+		}
+	}
+
+	return c.numCounted(vars)
+}
+
+// count returns the number of assignments to the counted variables in
+// clauses that can be extended to satisfy every clause. The result must
+// not be modified since it may be cached.
+func (c *counter) count(clauses [][]cnf.Lit) *big.Int {
+	before := c.countedIn(clauses)
+
+	// Assign every literal that is forced by unit propagation
+	clauses, assigned, ok := c.propagate(clauses)
+	if !ok {
+		return new(big.Int)
+	}
+
+	// Counted variables that were in the clauses and are neither assigned
+	// nor in the remaining clauses can have either value.
+	free := before - c.numCounted(assigned) - c.countedIn(clauses)
+	result := new(big.Int).Lsh(big.NewInt(1), uint(free))
+
+	// The count of independent components is the product of their counts
+	for _, comp := range c.components(clauses) {
+		n := c.countComponent(comp)
+		if n.Sign() == 0 {
+			return n
+		}
+
+		result.Mul(result, n)
+	}
+
+	return result
+}
+
+// countComponent returns the count for a single component. The component
+// must be fully propagated.
+func (c *counter) countComponent(comp [][]cnf.Lit) *big.Int {
+	key := componentKey(comp)
+	if result, ok := c.cache[key]; ok {
+		return result
+	}
+
+	var result *big.Int
+	if v := c.branchVar(comp); v == 0 {
+		// No counted variables remain so the only question is if the
+		// component can be satisfied at all.
+		result = new(big.Int)
+		if satisfiable(comp) {
+			result.SetInt64(1)
+		}
+	} else {
+		// Count both values of the variable by adding it as a unit
+		// clause. The capacity limit keeps the branches from sharing
+		// the appended clause.
+		comp = comp[:len(comp):len(comp)]
+		pos := c.count(append(comp, []cnf.Lit{cnf.NewLit(v, false)}))
+		neg := c.count(append(comp, []cnf.Lit{cnf.NewLit(v, true)}))
+		result = new(big.Int).Add(pos, neg)
+	}
+
+	c.cache[key] = result
+	return result
+}
+
+// branchVar returns the counted variable in clauses that is in the most
+// clauses, or zero if there are no counted variables.
+func (c *counter) branchVar(clauses [][]cnf.Lit) int {
+	for _, clause := range clauses {
+		for _, l := range clause {
+			c.occurs[l.Var()]++
+		}
+	}
+
+	// Ties are broken by the first variable found so the search is
+	// deterministic.
+	result, max := 0, 0
+	for _, clause := range clauses {
+		for _, l := range clause {
+			v := l.Var()
+			if n := c.occurs[v]; n > max && c.counted[v] {
+				result, max = v, n
+			}
+		}
+	}
+
+	for _, clause := range clauses {
+		for _, l := range clause {
+			c.occurs[l.Var()] = 0
+		}
+	}
+
+	return result
+}
+
+// propagate performs unit propagation on the clauses, returning the
+// simplified clauses and the assigned variables. This returns false if
+// there is a conflict.
+func (c *counter) propagate(clauses [][]cnf.Lit) ([][]cnf.Lit, []int, bool) {
+	var assigned []int
+	defer func() {
+		for _, v := range assigned {
+			c.values[cnf.NewLit(v, false)] = 0
+			c.values[cnf.NewLit(v, true)] = 0
+		}
+	}()
+
+	for {
+		// Assign every unit clause. Rather than simplifying the clauses
+		// after every assignment we assign all of the units that we can
+		// find first.
+		found := false
+		for _, clause := range clauses {
+			switch len(clause) {
+			case 0:
+				return nil, nil, false
+
+			case 1:
+				l := clause[0]
+				switch c.values[l] {
+				case -1:
+					return nil, nil, false
+
+				case 0:
+					c.values[l] = 1
+					c.values[l.Neg()] = -1
+					assigned = append(assigned, l.Var())
+					found = true
+				}
+			}
+		}
+
+		if !found {
+			return clauses, assigned, true
+		}
+
+		clauses = c.simplify(clauses)
+	}
+}
+
+// simplify returns the clauses under the current assignment: every
+// satisfied clause is removed and every false literal is removed from the
+// remaining clauses. The clauses aren't modified.
+func (c *counter) simplify(clauses [][]cnf.Lit) [][]cnf.Lit {
+	result := make([][]cnf.Lit, 0, len(clauses))
+CLAUSES:
+	for _, clause := range clauses {
+		falseCount := 0
+		for _, l := range clause {
+			switch c.values[l] {
+			case 1:
+				continue CLAUSES
+
+			case -1:
+				falseCount++
+			}
+		}
+
+		if falseCount == 0 {
+			result = append(result, clause)
+			continue
+		}
+
+		lits := make([]cnf.Lit, 0, len(clause)-falseCount)
+		for _, l := range clause {
+			if c.values[l] == 0 {
+				lits = append(lits, l)
+			}
+		}
+
+		result = append(result, lits)
+	}
+
+	return result
+}
+
+// components splits the clauses into groups that share no variables.
+func (c *counter) components(clauses [][]cnf.Lit) [][][]cnf.Lit {
+	// Union-find over the variables. The parents are reset to zero once
+	// we're done.
+	find := func(v int) int {
+		for c.parent[v] != v {
+			c.parent[v] = c.parent[c.parent[v]]
+			v = c.parent[v]
+		}
+
+		return v
+	}
+
+	for _, clause := range clauses {
+		for _, l := range clause {
+			if v := l.Var(); c.parent[v] == 0 {
+				c.parent[v] = v
+			}
+		}
+	}
+
+	for _, clause := range clauses {
+		root := find(clause[0].Var())
+		for _, l := range clause[1:] {
+			if other := find(l.Var()); other != root {
+				c.parent[other] = root
+			}
+		}
+	}
+
+	// Group the clauses by the root of their variables, keeping the
+	// components in the order they first appear. The index of the
+	// component of each root is stored plus one, so zero means that the
+	// root has no component yet.
+	var result [][][]cnf.Lit
+	for _, clause := range clauses {
+		root := find(clause[0].Var())
+		if c.component[root] == 0 {
+			result = append(result, nil)
+			c.component[root] = len(result)
+		}
+
+		i := c.component[root] - 1
+		result[i] = append(result[i], clause)
+	}
+
+	for _, clause := range clauses {
+		for _, l := range clause {
+			c.component[l.Var()] = 0
+			c.parent[l.Var()] = 0
+		}
+	}
+
+	return result
+}
+
+// componentKey returns a key for the cache that is the same for any two
+// components with the same clauses.
+//
+// Simplifying the formula and splitting it into components keeps the
+// clauses in the same relative order as the original formula, so the same
+// component always has its clauses in the same order and we don't have to
+// sort them.
+func componentKey(comp [][]cnf.Lit) string {
+	var buf []byte
+	for _, clause := range comp {
+		for _, l := range clause {
+			buf = strconv.AppendInt(buf, int64(l), 36)
+			buf = append(buf, ' ')
+		}
+		buf = append(buf, ',')
+	}
+
+	return string(buf)
+}
+
+// satisfiable returns true if the clauses can be satisfied.
+func satisfiable(clauses [][]cnf.Lit) bool {
+	s := sat.New()
+	for _, clause := range clauses {
+		// AddClause modifies the clause so give it a copy
+		s.AddClause(cnf.Clause(append([]cnf.Lit(nil), clause...)))
+	}
+
+	return s.Solve()
+}
@@ -0,0 +1,254 @@
+package count
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/mitchellh/go-sat"
+	"github.com/mitchellh/go-sat/cnf"
+	"github.com/mitchellh/go-sat/dimacs"
+)
+
+func TestCount(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Formula [][]int
+		Result  int64
+	}{
+		{
+			"empty",
+			nil,
+			1,
+		},
+
+		{
+			"unsat",
+			[][]int{
+				[]int{1},
+				[]int{-1},
+			},
+			0,
+		},
+
+		{
+			"or",
+			[][]int{
+				[]int{1, 2, 3},
+			},
+			7,
+		},
+
+		{
+			"unused vars",
+			[][]int{
+				[]int{1},
+				[]int{4},
+			},
+			4,
+		},
+
+		{
+			"tautology",
+			[][]int{
+				[]int{1, -1},
+				[]int{2, 2},
+			},
+			2,
+		},
+
+		{
+			"components",
+			[][]int{
+				[]int{1, 2},
+				[]int{3, 4},
+				[]int{-3, -4},
+			},
+			6,
+		},
+
+		{
+			"chain",
+			[][]int{
+				[]int{-1, 2},
+				[]int{-2, 3},
+				[]int{-3, 4},
+			},
+			5,
+		},
+
+		{
+			"propagation",
+			[][]int{
+				[]int{1},
+				[]int{-1, 2},
+				[]int{-2, 3, 4},
+			},
+			3,
+		},
+
+		{
+			"3-cnf",
+			[][]int{
+				[]int{1, 2, -3},
+				[]int{-1, 3, 4},
+				[]int{2, -4, 5},
+				[]int{-2, -5, 1},
+				[]int{3, 4, 5},
+				[]int{-1, -2, -3},
+			},
+			11,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d-%s", i, tc.Name), func(t *testing.T) {
+			actual := Count(cnf.NewFormulaFromInts(tc.Formula))
+			if actual.Cmp(big.NewInt(tc.Result)) != 0 {
+				t.Fatalf("bad: %s", actual)
+			}
+		})
+	}
+}
+
+func TestCount_big(t *testing.T) {
+	// 200 independent clauses (x ∨ y) have 3^200 solutions
+	var ints [][]int
+	for i := 0; i < 200; i++ {
+		ints = append(ints, []int{2*i + 1, 2*i + 2})
+	}
+
+	expected := new(big.Int).Exp(big.NewInt(3), big.NewInt(200), nil)
+	if actual := Count(cnf.NewFormulaFromInts(ints)); actual.Cmp(expected) != 0 {
+		t.Fatalf("bad: %s", actual)
+	}
+}
+
+func TestCountProjected(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Formula [][]int
+		Vars    []int
+		Result  int64
+	}{
+		{
+			"none",
+			[][]int{
+				[]int{1, 2},
+			},
+			nil,
+			1,
+		},
+
+		{
+			"none unsat",
+			[][]int{
+				[]int{1},
+				[]int{-1},
+			},
+			nil,
+			0,
+		},
+
+		{
+			"hidden",
+			[][]int{
+				[]int{1, 2, 3},
+				[]int{-1, -2},
+			},
+			[]int{1, 2},
+			3,
+		},
+
+		{
+			"unused",
+			[][]int{
+				[]int{1, 2},
+			},
+			[]int{3, 3},
+			2,
+		},
+
+		{
+			"components",
+			[][]int{
+				[]int{1, 2},
+				[]int{3, 4},
+				[]int{-3, -4},
+			},
+			[]int{1, 3},
+			4,
+		},
+
+		{
+			"3-cnf",
+			[][]int{
+				[]int{1, 2, -3},
+				[]int{-1, 3, 4},
+				[]int{2, -4, 5},
+				[]int{-2, -5, 1},
+				[]int{3, 4, 5},
+				[]int{-1, -2, -3},
+			},
+			[]int{2, 4, 5},
+			6,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d-%s", i, tc.Name), func(t *testing.T) {
+			actual := CountProjected(cnf.NewFormulaFromInts(tc.Formula), tc.Vars)
+			if actual.Cmp(big.NewInt(tc.Result)) != 0 {
+				t.Fatalf("bad: %s", actual)
+			}
+		})
+	}
+}
+
+func TestCount_satlib(t *testing.T) {
+	dir := filepath.Join("..", "testdata", "satlib", "sat-uniform-20-91")
+	paths, err := filepath.Glob(filepath.Join(dir, "*.cnf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths[:10] {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			// Count the solutions by enumerating them with the solver
+			s := sat.New()
+			s.AddFormula(parse(t, path).Formula)
+			expected := int64(0)
+			err := s.Enumerate(context.Background(), nil, func(map[int]bool) bool {
+				expected++
+				return true
+			})
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if actual := Count(parse(t, path).Formula); actual.Int64() != expected {
+				t.Fatalf("bad: %s != %d", actual, expected)
+			}
+		})
+	}
+}
+
+func parse(t *testing.T, path string) *dimacs.Problem {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+
+	p, err := dimacs.Parse(f)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return p
+}
@@ -1,11 +1,14 @@
-// Package dimacs parses the DIMACS CNF format.
+// Package dimacs parses and writes the DIMACS CNF format and the WCNF
+// format for MaxSAT problems.
 //
 // DIMACS CNF is a common format used to represent boolean expressions in
 // conjunctive normal form. It is often used as a way to test SAT solvers.
 //
-// This package will only parse the CNF problem type in the file. If the file
+// Parse will only parse the CNF problem type in the file. If the file
 // contains any other problem type then parsing will fail even if it is a
-// valid syntax otherwise.
+// valid syntax otherwise. Weighted MaxSAT problems in the WCNF format are
+// parsed with ParseWCNF instead. ParseFile and Decompress handle input
+// compressed with gzip, bzip2 or xz.
 //
 // The full DIMACS CNF format is explained here:
 // http://www.domagoj-babic.com/uploads/ResearchProjects/Spear/dimacs-cnf.pdf
@@ -13,8 +16,6 @@ package dimacs
 
 import (
 	"bufio"
-	"bytes"
-	"fmt"
 	"io"
 	"strconv"
 
@@ -25,110 +26,269 @@ import (
 //
 // Variables and Clauses will reflect exactly what is read from the
 // problem line in the DIMACS file. This isn't validated with the number
-// of variables or clauses read in the file itself.
+// of variables or clauses read in the file itself unless the file is
+// parsed by a strict Parser.
 type Problem struct {
 	Variables int         // Variables is number of declared variables
 	Clauses   int         // Clauses is number of declared clauses
 	Formula   cnf.Formula // Formula is the actual boolean formula
+
+	// Comments are the comment lines before the problem line, without the
+	// leading "c".
+	Comments []string
 }
 
 // Parse parses the given input buffer in DIMAC CNF form and returns
 // the parsed problem.
 func Parse(r io.Reader) (*Problem, error) {
+	var p Parser
+	return p.Parse(r)
+}
+
+// ParseInto parses the given input buffer in DIMACS CNF form, adding every
+// clause to dst as it is parsed. The returned problem has no Formula.
+//
+// This is useful for loading large files into a solver without holding
+// the whole formula in memory twice.
+func ParseInto(r io.Reader, dst ClauseAdder) (*Problem, error) {
+	var p Parser
+	return p.ParseInto(r, dst)
+}
+
+// ParseFunc parses the given input buffer in DIMACS CNF form, calling fn
+// with each clause as it is parsed rather than building the formula. The
+// returned problem has no Formula.
+//
+// Each clause passed to fn is newly allocated, so fn may keep or modify
+// it. If fn returns an error then parsing stops and the error is returned.
+func ParseFunc(r io.Reader, fn func(cnf.Clause) error) (*Problem, error) {
+	var p Parser
+	return p.ParseFunc(r, fn)
+}
+
+// ClauseAdder is implemented by anything clauses can be added to, such as
+// *sat.Solver.
+type ClauseAdder interface {
+	AddClause(cnf.Clause)
+}
+
+// Parser parses DIMACS CNF. The zero value is a lenient parser, which is
+// what the package level functions use.
+//
+// Errors in the input, including errors reading it, are always returned
+// as an *Error with the position of the problem.
+type Parser struct {
+	// Strict validates that the input matches the problem line. By
+	// default the problem line isn't checked, parsing stops once the
+	// declared number of clauses is read and a clause that isn't
+	// terminated by 0 at the end of the input is dropped. In strict mode
+	// all of these are errors:
+	//
+	//   - the number of clauses doesn't match the problem line
+	//   - a variable is larger than the number of variables declared
+	//   - the last clause isn't terminated by 0
+	//   - anything other than comments follows the last clause
+	//   - there is no problem line
+	//
+	// Notably the SATLIB benchmarks end with a "%" line that is rejected
+	// in strict mode.
+	Strict bool
+
+	// CheckVariables returns an error for a variable larger than the
+	// number of variables declared on the problem line, as in strict
+	// mode, without the other checks. This bounds the memory needed to
+	// add the clauses to a solver, which allocates every variable up to
+	// the largest one.
+	CheckVariables bool
+}
+
+// Parse parses the given input buffer in DIMACS CNF form and returns the
+// parsed problem.
+func (p *Parser) Parse(r io.Reader) (*Problem, error) {
+	var formula cnf.Formula
+	result, err := p.ParseFunc(r, func(c cnf.Clause) error {
+		formula = append(formula, c)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Formula = formula
+	return result, nil
+}
+
+// ParseInto is like the package level ParseInto using this parser.
+func (p *Parser) ParseInto(r io.Reader, dst ClauseAdder) (*Problem, error) {
+	return p.ParseFunc(r, func(c cnf.Clause) error {
+		dst.AddClause(c)
+		return nil
+	})
+}
+
+// ParseFunc is like the package level ParseFunc using this parser.
+func (p *Parser) ParseFunc(r io.Reader, fn func(cnf.Clause) error) (*Problem, error) {
 	// Initialize the result
 	var result Problem
 	result.Variables = -1
 
-	// Current is the currently tracked clause
+	// Current is the currently tracked clause and start is the position
+	// of its first literal.
 	var current []cnf.Lit
+	var startLine, startColumn int
+
+	// The position of the clause count on the problem line
+	var headerLine, headerColumn int
 
 	// Create a bufio scanner so we can break it up by line
-	scanner := bufio.NewScanner(r)
+	scanner := newScanner(r)
+	line := 0
 	read := 0
 	for scanner.Scan() {
 		raw := scanner.Bytes()
+		line++
 
 		// If the line is blank, skip
 		if len(raw) == 0 {
 			continue
 		}
 
+		// Comments can be anywhere but we only keep the ones before the
+		// problem line.
+		if raw[0] == 'c' {
+			if result.Variables == -1 {
+				result.Comments = append(result.Comments, comment(raw))
+			}
+
+			continue
+		}
+
+		fields := fields(raw)
+
 		// If we don't know the number of variables we still haven't
-		// seen the problem line. Look for comments or the problem.
+		// seen the problem line.
 		if result.Variables == -1 {
-			switch raw[0] {
-			case 'c':
-				// Ignore, comment line
-
-			case 'p':
-				// Problem line found!
-				fields := bytes.Fields(raw)
-				if len(fields) != 4 {
-					return nil, fmt.Errorf(
-						"problem line should have 4 fields whitespace separated: %q", raw)
-				}
-
-				if string(fields[1]) != "cnf" {
-					return nil, fmt.Errorf(
-						"problem type must be 'cnf', got: %q", fields[1])
-				}
+			if raw[0] != 'p' {
+				return nil, errorf(line, 1,
+					"invalid start of line character: %q", raw[0])
+			}
 
-				vars, err := strconv.Atoi(string(fields[2]))
-				if err != nil {
-					return nil, fmt.Errorf(
-						"error converting variable count %q: %s", fields[2], err)
-				}
+			// Problem line found!
+			if len(fields) != 4 {
+				return nil, errorf(line, 1,
+					"problem line should have 4 fields whitespace separated: %q", raw)
+			}
 
-				clauses, err := strconv.Atoi(string(fields[3]))
-				if err != nil {
-					return nil, fmt.Errorf(
-						"error converting clauses count %q: %s", fields[3], err)
-				}
+			if string(fields[1].text) != "cnf" {
+				return nil, errorf(line, fields[1].column,
+					"problem type must be 'cnf', got: %q", fields[1].text)
+			}
 
-				result.Variables = vars
-				result.Clauses = clauses
+			vars, err := strconv.Atoi(string(fields[2].text))
+			if err != nil || (p.Strict && vars < 0) {
+				return nil, errorf(line, fields[2].column,
+					"invalid variable count %q", fields[2].text)
+			}
 
-			default:
-				return nil, fmt.Errorf(
-					"invalid start of line character: %q", raw[0])
+			clauses, err := strconv.Atoi(string(fields[3].text))
+			if err != nil || (p.Strict && clauses < 0) {
+				return nil, errorf(line, fields[3].column,
+					"invalid clause count %q", fields[3].text)
 			}
 
+			result.Variables = vars
+			result.Clauses = clauses
+			headerLine, headerColumn = line, fields[3].column
 			continue
 		}
 
-		// Read the line
-		fields := bytes.Fields(raw)
-
 		// Read all the literals
-		end := false
-		for _, raw := range fields {
-			val, err := strconv.Atoi(string(raw))
-			if err != nil {
-				return nil, fmt.Errorf(
-					"invalid literal %q", raw)
+		for _, f := range fields {
+			// In strict mode we keep reading after the last clause to
+			// make sure that there is nothing else.
+			if p.Strict && read >= result.Clauses {
+				return nil, errorf(line, f.column,
+					"unexpected %q after the last of %d clauses", f.text, result.Clauses)
 			}
 
-			if val == 0 {
-				end = true
-				break
+			val, err := strconv.Atoi(string(f.text))
+			if err != nil {
+				return nil, errorf(line, f.column, "invalid literal %q", f.text)
 			}
 
-			current = append(current, cnf.NewLitInt(val))
-		}
+			if val != 0 {
+				if (p.Strict || p.CheckVariables) &&
+					(val > result.Variables || -val > result.Variables) {
+					return nil, errorf(line, f.column,
+						"variable %d is larger than the %d variables declared",
+						cnf.NewLitInt(val).Var(), result.Variables)
+				}
+
+				if len(current) == 0 {
+					startLine, startColumn = line, f.column
+				}
+
+				current = append(current, cnf.NewLitInt(val))
+				continue
+			}
 
-		// If we found the end, compile the clause
-		if end {
-			result.Formula = append(result.Formula, cnf.Clause(current))
+			// We found the end, compile the clause
+			if err := fn(cnf.Clause(current)); err != nil {
+				return nil, err
+			}
 			current = nil
 
-			// Increment our count. If we've read all our expected clauses,
-			// then we're done.
+			// Increment our count. If we've read all our expected clauses
+			// and we're not strict, then we're done.
 			read++
-			if read >= result.Clauses {
-				break
+			if read >= result.Clauses && !p.Strict {
+				return &result, nil
 			}
 		}
 	}
+	if err := scanner.Err(); err != nil {
+		// The error is from reading the line after the last one scanned
+		return nil, errorf(line+1, 1, "%s", err)
+	}
+
+	if p.Strict {
+		switch {
+		case result.Variables == -1:
+			return nil, errorf(line+1, 1, "missing problem line")
+
+		case len(current) > 0:
+			return nil, errorf(startLine, startColumn,
+				"clause is not terminated by 0")
+
+		case read != result.Clauses:
+			return nil, errorf(headerLine, headerColumn,
+				"problem line declares %d clauses but %d were found",
+				result.Clauses, read)
+		}
+	}
 
 	return &result, nil
 }
+
+// comment returns the text of a comment line: everything after the "c"
+// and the space that follows it.
+func comment(raw []byte) string {
+	raw = raw[1:]
+	if len(raw) > 0 && raw[0] == ' ' {
+		raw = raw[1:]
+	}
+
+	return string(raw)
+}
+
+// maxLineSize is the longest line that can be parsed. Generated problems
+// can have very long clauses or many clauses on a single line.
+const maxLineSize = 1 << 30
+
+// newScanner returns a scanner for the lines of r that allows lines up to
+// maxLineSize. The buffer only grows as long lines are read.
+func newScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineSize)
+	return scanner
+}
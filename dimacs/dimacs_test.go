@@ -1,10 +1,15 @@
 package dimacs
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestParse(t *testing.T) {
@@ -104,3 +109,215 @@ whatever
 		})
 	}
 }
+
+func TestParse_multipleClausesPerLine(t *testing.T) {
+	result, err := Parse(strings.NewReader("p cnf 3 3\n1 0 -2 3 0\n-3 0\n"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := [][]int{
+		[]int{1},
+		[]int{-2, 3},
+		[]int{-3},
+	}
+	if actual := result.Formula.Int(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestParser_strict(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Input  string
+		Line   int // zero if there is no error
+		Column int
+	}{
+		{
+			"valid",
+			`c Foo
+p cnf 3 2
+1 -3
+c Bar
+2 0
+-1 0
+
+c Baz
+`,
+			0, 0,
+		},
+
+		{
+			"too few clauses",
+			`p cnf 3 3
+1 -3 0
+2 0
+`,
+			1, 9,
+		},
+
+		{
+			"too many clauses",
+			`p cnf 3 1
+1 -3 0
+2 0
+`,
+			3, 1,
+		},
+
+		{
+			"variable out of range",
+			`p cnf 3 2
+1 -3 0
+2  -4 0
+`,
+			3, 4,
+		},
+
+		{
+			"unterminated clause",
+			`p cnf 3 2
+1 -3 0
+  2
+  3
+`,
+			3, 3,
+		},
+
+		{
+			"trailing garbage",
+			`p cnf 1 1
+1 0
+%
+0
+`,
+			3, 1,
+		},
+
+		{
+			"trailing garbage same line",
+			`p cnf 1 1
+1 0 x
+`,
+			2, 5,
+		},
+
+		{
+			"missing problem line",
+			`c Foo
+`,
+			2, 1,
+		},
+
+		{
+			"negative count",
+			`p cnf 1 -1
+`,
+			1, 9,
+		},
+
+		{
+			"invalid literal",
+			`p cnf 1 1
+1x 0
+`,
+			2, 1,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d-%s", i, tc.Name), func(t *testing.T) {
+			p := &Parser{Strict: true}
+			_, err := p.Parse(strings.NewReader(tc.Input))
+			if tc.Line == 0 {
+				if err != nil {
+					t.Fatalf("err: %s", err)
+				}
+
+				return
+			}
+
+			perr, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("bad: %#v", err)
+			}
+			if perr.Line != tc.Line || perr.Column != tc.Column {
+				t.Fatalf("bad: %s", perr)
+			}
+		})
+	}
+}
+
+func TestParser_checkVariables(t *testing.T) {
+	// The other strict checks are skipped
+	p := &Parser{CheckVariables: true}
+	_, err := p.Parse(strings.NewReader("p cnf 3 1\n1 -3 0\n%\n"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	_, err = p.Parse(strings.NewReader("p cnf 3 1\n1 -2000000000 0\n"))
+	perr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("bad: %#v", err)
+	}
+	if perr.Line != 2 || perr.Column != 3 {
+		t.Fatalf("bad: %s", perr)
+	}
+}
+
+func TestParse_errorPosition(t *testing.T) {
+	_, err := Parse(strings.NewReader("c Foo\np cnf 2 2\n1 2 0\n-1 y 0\n"))
+	perr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	expected := "line 4, column 4: invalid literal \"y\""
+	if perr.Error() != expected {
+		t.Fatalf("bad: %s", perr)
+	}
+}
+
+func TestParse_readError(t *testing.T) {
+	r := io.MultiReader(
+		strings.NewReader("p cnf 2 2\n1 2 0\n"),
+		iotest.ErrReader(errors.New("boom")))
+	_, err := Parse(r)
+	perr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("bad: %#v", err)
+	}
+
+	expected := "line 3, column 1: boom"
+	if perr.Error() != expected {
+		t.Fatalf("bad: %s", perr)
+	}
+}
+
+func TestParser_strictSatlib(t *testing.T) {
+	// The uniform problems end with "%" so only the DIMACS problems are
+	// well formed.
+	paths, err := filepath.Glob(filepath.Join(
+		"..", "testdata", "satlib", "unsat-dimacs-dubois", "*.cnf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no satlib problems")
+	}
+
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		p := &Parser{Strict: true}
+		_, err = p.Parse(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+	}
+}
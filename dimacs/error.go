@@ -0,0 +1,58 @@
+package dimacs
+
+import (
+	"fmt"
+)
+
+// Error is an error in the input of a parser, with the position of the
+// problem. Line and Column start at 1 and Column counts bytes.
+type Error struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// errorf returns an *Error at the given position.
+func errorf(line, column int, format string, args ...interface{}) error {
+	return &Error{Line: line, Column: column, Msg: fmt.Sprintf(format, args...)}
+}
+
+// field is a whitespace separated field of a line and the column that it
+// starts at.
+type field struct {
+	text   []byte
+	column int
+}
+
+// fields splits the line into whitespace separated fields like
+// bytes.Fields, keeping the column of each field.
+func fields(raw []byte) []field {
+	var result []field
+	start := -1
+	for i := 0; i <= len(raw); i++ {
+		space := i == len(raw) || isSpace(raw[i])
+		switch {
+		case !space && start == -1:
+			start = i
+
+		case space && start != -1:
+			result = append(result, field{text: raw[start:i], column: start + 1})
+			start = -1
+		}
+	}
+
+	return result
+}
+
+func isSpace(b byte) bool {
+	switch b {
+	case ' ', '\t', '\r', '\v', '\f':
+		return true
+	}
+
+	return false
+}
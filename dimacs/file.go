@@ -0,0 +1,66 @@
+package dimacs
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"os"
+
+	"github.com/mitchellh/go-sat/internal/xz"
+)
+
+// Magic bytes at the start of the supported compressed formats
+var (
+	gzipMagic  = []byte{0x1F, 0x8B}
+	bzip2Magic = []byte{'B', 'Z', 'h'}
+)
+
+// ParseFile parses the DIMACS CNF file at path. The file may be
+// compressed with gzip, bzip2 or xz, which is detected from its contents
+// rather than its name.
+func ParseFile(path string) (*Problem, error) {
+	var p Parser
+	return p.ParseFile(path)
+}
+
+// ParseFile is like the package level ParseFile using this parser.
+func (p *Parser) ParseFile(path string) (*Problem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := Decompress(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Parse(r)
+}
+
+// Decompress returns a reader that decompresses r if it starts with the
+// magic bytes of gzip, bzip2 or xz data. Otherwise the returned reader
+// reads r unchanged.
+func Decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	// Peek returns an error if there are fewer bytes than requested, which
+	// is fine since we only compare what we have.
+	magic, _ := br.Peek(len(xz.Magic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		return gzip.NewReader(br)
+
+	case bytes.HasPrefix(magic, bzip2Magic):
+		return bzip2.NewReader(br), nil
+
+	case bytes.HasPrefix(magic, xz.Magic):
+		return xz.NewReader(br)
+
+	default:
+		return br, nil
+	}
+}
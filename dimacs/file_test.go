@@ -0,0 +1,74 @@
+package dimacs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseFile(t *testing.T) {
+	expected, err := ParseFile(filepath.Join(
+		"..", "testdata", "satlib", "sat-uniform-20-91", "uf20-01.cnf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(expected.Formula) != 91 {
+		t.Fatalf("bad: %d", len(expected.Formula))
+	}
+
+	for _, name := range []string{"uf20-01.cnf.gz", "uf20-01.cnf.bz2", "uf20-01.cnf.xz"} {
+		t.Run(name, func(t *testing.T) {
+			actual, err := ParseFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if !reflect.DeepEqual(actual, expected) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+}
+
+func TestParseFile_missing(t *testing.T) {
+	if _, err := ParseFile(filepath.Join("testdata", "missing.cnf")); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	cases := []struct {
+		Name  string
+		Input []byte
+		Err   bool
+	}{
+		{"empty", nil, false},
+		{"short", []byte{0x1F}, false},
+		{"plain", []byte("p cnf 1 1\n1 0\n"), false},
+		{"bad gzip", []byte{0x1F, 0x8B, 0x00}, true},
+		{"bad xz", []byte{0xFD, '7', 'z', 'X', 'Z', 0x00, 0x00}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			r, err := Decompress(bytes.NewReader(tc.Input))
+			if (err != nil) != tc.Err {
+				t.Fatalf("bad: %s", err)
+			}
+			if err != nil {
+				return
+			}
+
+			// Uncompressed input is returned unchanged
+			actual, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if !bytes.Equal(actual, tc.Input) {
+				t.Fatalf("bad: %q", actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package dimacs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+const streamInput = `c Foo
+p cnf 3 3
+1 -3 0
+2 1 3 0
+-2 0
+`
+
+func TestParseFunc(t *testing.T) {
+	var actual [][]int
+	p, err := ParseFunc(strings.NewReader(streamInput), func(c cnf.Clause) error {
+		actual = append(actual, c.Int())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := [][]int{
+		[]int{1, -3},
+		[]int{2, 1, 3},
+		[]int{-2},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+	if p.Variables != 3 || p.Clauses != 3 || p.Formula != nil {
+		t.Fatalf("bad: %#v", p)
+	}
+}
+
+func TestParseFunc_error(t *testing.T) {
+	expected := errors.New("stop")
+	count := 0
+	_, err := ParseFunc(strings.NewReader(streamInput), func(c cnf.Clause) error {
+		count++
+		if count == 2 {
+			return expected
+		}
+
+		return nil
+	})
+	if err != expected {
+		t.Fatalf("err: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("bad: %d", count)
+	}
+}
+
+func TestParseFunc_longLine(t *testing.T) {
+	// A single clause on a line longer than the default scanner limit
+	var buf bytes.Buffer
+	buf.WriteString("p cnf 20000 1\n")
+	for v := 1; v <= 20000; v++ {
+		fmt.Fprintf(&buf, "%d ", v)
+	}
+	buf.WriteString("0\n")
+	if buf.Len() < 64*1024 {
+		t.Fatalf("bad: %d", buf.Len())
+	}
+
+	var actual []int
+	_, err := ParseFunc(&buf, func(c cnf.Clause) error {
+		actual = append(actual, len(c))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(actual, []int{20000}) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestParseInto(t *testing.T) {
+	var dst clauseList
+	if _, err := ParseInto(strings.NewReader(streamInput), &dst); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The clauses are owned by dst, so modifying one must not affect the
+	// others.
+	dst[0][0] = cnf.NewLitInt(5)
+	expected := [][]int{
+		[]int{5, -3},
+		[]int{2, 1, 3},
+		[]int{-2},
+	}
+	if actual := cnf.Formula(dst).Int(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+// clauseList is a ClauseAdder that records every clause.
+type clauseList []cnf.Clause
+
+func (l *clauseList) AddClause(c cnf.Clause) {
+	*l = append(*l, c)
+}
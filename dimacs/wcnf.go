@@ -0,0 +1,245 @@
+package dimacs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+// WeightedProblem is a weighted partial MaxSAT problem from a WCNF file.
+//
+// There are two WCNF formats. The old format has a problem line
+// "p wcnf <variables> <clauses> <top>" and every clause starts with its
+// weight. Clauses with a weight of at least the top weight are hard. The
+// new format used since the 2022 MaxSAT Evaluation has no problem line;
+// hard clauses start with "h" and soft clauses start with their weight.
+type WeightedProblem struct {
+	// Variables, Clauses and Top are exactly what is read from the problem
+	// line of the old format. They are all zero for the new format. If the
+	// problem line has no top weight then every clause is soft and Top
+	// is zero.
+	Variables int
+	Clauses   int
+	Top       uint64
+
+	// Comments are the comment lines before the first clause, without the
+	// leading "c".
+	Comments []string
+
+	Hard cnf.Formula      // Hard clauses which must be satisfied
+	Soft []WeightedClause // Soft clauses with the cost of not satisfying them
+}
+
+// WeightedClause is a soft clause and its weight.
+type WeightedClause struct {
+	Clause cnf.Clause
+	Weight uint64
+}
+
+// ParseWCNF parses the given input buffer in either WCNF format and returns
+// the parsed problem. The format is detected from the first line that
+// isn't a comment: only the old format starts with a problem line.
+// Every clause must be terminated by 0 and in the old format the number
+// of clauses must match the problem line. Errors in the input are
+// returned as an *Error.
+func ParseWCNF(r io.Reader) (*WeightedProblem, error) {
+	var result WeightedProblem
+
+	// header is true once the first line that isn't a comment is seen and
+	// old is true if that was a problem line.
+	header := false
+	old := false
+
+	// Current is the currently tracked clause. A clause starts with its
+	// weight, so inClause is false until the weight is read. The weight is
+	// at startLine and startColumn.
+	var current []cnf.Lit
+	var weight uint64
+	var startLine, startColumn int
+	hard := false
+	inClause := false
+
+	// The position of the clause count on the problem line
+	var headerLine, headerColumn int
+
+	scanner := newScanner(r)
+	line := 0
+	for scanner.Scan() {
+		raw := scanner.Bytes()
+		line++
+
+		// Skip blank lines and comments, keeping the comments before the
+		// first clause.
+		if len(raw) == 0 {
+			continue
+		}
+		if raw[0] == 'c' {
+			if len(result.Hard)+len(result.Soft) == 0 && !inClause {
+				result.Comments = append(result.Comments, comment(raw))
+			}
+
+			continue
+		}
+
+		if !header {
+			header = true
+			if raw[0] == 'p' {
+				if err := parseWCNFHeader(line, raw, &result); err != nil {
+					return nil, err
+				}
+
+				old = true
+				headerLine, headerColumn = line, fields(raw)[3].column
+				continue
+			}
+		}
+
+		for _, f := range fields(raw) {
+			if !inClause {
+				inClause = true
+				startLine, startColumn = line, f.column
+				if !old && string(f.text) == "h" {
+					hard = true
+					continue
+				}
+
+				w, err := strconv.ParseUint(string(f.text), 10, 64)
+				if err != nil {
+					return nil, errorf(line, f.column, "invalid weight %q", f.text)
+				}
+
+				weight = w
+				hard = old && result.Top > 0 && w >= result.Top
+				continue
+			}
+
+			val, err := strconv.Atoi(string(f.text))
+			if err != nil {
+				return nil, errorf(line, f.column, "invalid literal %q", f.text)
+			}
+
+			if val != 0 {
+				current = append(current, cnf.NewLitInt(val))
+				continue
+			}
+
+			// End of the clause
+			if hard {
+				result.Hard = append(result.Hard, cnf.Clause(current))
+			} else {
+				result.Soft = append(result.Soft, WeightedClause{
+					Clause: cnf.Clause(current),
+					Weight: weight,
+				})
+			}
+
+			current = nil
+			inClause = false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		// The error is from reading the line after the last one scanned
+		return nil, errorf(line+1, 1, "%s", err)
+	}
+
+	if inClause {
+		return nil, errorf(startLine, startColumn,
+			"clause is not terminated by 0")
+	}
+
+	if read := len(result.Hard) + len(result.Soft); old && read != result.Clauses {
+		return nil, errorf(headerLine, headerColumn,
+			"problem line declares %d clauses but %d were found",
+			result.Clauses, read)
+	}
+
+	return &result, nil
+}
+
+// parseWCNFHeader parses the problem line of the old WCNF format.
+func parseWCNFHeader(line int, raw []byte, result *WeightedProblem) error {
+	fields := fields(raw)
+	if len(fields) != 4 && len(fields) != 5 {
+		return errorf(line, 1,
+			"problem line should have 4 or 5 fields whitespace separated: %q", raw)
+	}
+
+	if string(fields[1].text) != "wcnf" {
+		return errorf(line, fields[1].column,
+			"problem type must be 'wcnf', got: %q", fields[1].text)
+	}
+
+	vars, err := strconv.Atoi(string(fields[2].text))
+	if err != nil {
+		return errorf(line, fields[2].column,
+			"invalid variable count %q", fields[2].text)
+	}
+
+	clauses, err := strconv.Atoi(string(fields[3].text))
+	if err != nil {
+		return errorf(line, fields[3].column,
+			"invalid clause count %q", fields[3].text)
+	}
+
+	if len(fields) == 5 {
+		top, err := strconv.ParseUint(string(fields[4].text), 10, 64)
+		if err != nil {
+			return errorf(line, fields[4].column,
+				"invalid top weight %q", fields[4].text)
+		}
+
+		result.Top = top
+	}
+
+	result.Variables = vars
+	result.Clauses = clauses
+	return nil
+}
+
+// WriteWCNF writes the problem to w in WCNF. If Top is zero the problem is
+// written in the new format. Otherwise it is written in the old format
+// with Top as the top weight, which must be larger than the weight of
+// every soft clause; the number of variables and clauses on the problem
+// line are computed from the clauses. The comments are written first.
+func WriteWCNF(w io.Writer, p *WeightedProblem) error {
+	bw := bufio.NewWriter(w)
+
+	writeComments(bw, p.Comments)
+
+	hardWeight := "h"
+	if p.Top > 0 {
+		soft := make(cnf.Formula, len(p.Soft))
+		for i, c := range p.Soft {
+			if c.Weight >= p.Top {
+				return fmt.Errorf(
+					"soft clause weight %d must be less than top weight %d",
+					c.Weight, p.Top)
+			}
+
+			soft[i] = c.Clause
+		}
+
+		fmt.Fprintf(bw, "p wcnf %d %d %d\n",
+			maxVar(p.Hard, soft), len(p.Hard)+len(p.Soft), p.Top)
+		hardWeight = strconv.FormatUint(p.Top, 10)
+	}
+
+	var buf []byte
+	for _, c := range p.Hard {
+		buf = append(buf[:0], hardWeight...)
+		buf = append(buf, ' ')
+		buf = appendClause(buf, c)
+		bw.Write(buf)
+	}
+	for _, c := range p.Soft {
+		buf = strconv.AppendUint(buf[:0], c.Weight, 10)
+		buf = append(buf, ' ')
+		buf = appendClause(buf, c.Clause)
+		bw.Write(buf)
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,365 @@
+package dimacs
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+func TestParseWCNF(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Input  string
+		Err    bool
+		Hard   [][]int
+		Soft   [][]int // weight followed by the literals
+		Header [3]uint64
+	}{
+		{
+			"old format",
+			`c Foo
+p wcnf 3 4 10
+10 1 -2 0
+10 -3 0
+4 2 0
+1 3 -1 0
+`,
+			false,
+			[][]int{
+				[]int{1, -2},
+				[]int{-3},
+			},
+			[][]int{
+				[]int{4, 2},
+				[]int{1, 3, -1},
+			},
+			[3]uint64{3, 4, 10},
+		},
+
+		{
+			"old format without top",
+			`p wcnf 2 2
+3 1 2 0
+5 -1
+0
+`,
+			false,
+			nil,
+			[][]int{
+				[]int{3, 1, 2},
+				[]int{5, -1},
+			},
+			[3]uint64{2, 2, 0},
+		},
+
+		{
+			"new format",
+			`c Foo
+h 1 -2 0
+
+c Bar
+h -3 0
+4 2 0
+1099511627776 3 -1 0
+`,
+			false,
+			[][]int{
+				[]int{1, -2},
+				[]int{-3},
+			},
+			[][]int{
+				[]int{4, 2},
+				[]int{1099511627776, 3, -1},
+			},
+			[3]uint64{},
+		},
+
+		{
+			"empty clauses",
+			`h 0
+3 0
+`,
+			false,
+			[][]int{
+				[]int{},
+			},
+			[][]int{
+				[]int{3},
+			},
+			[3]uint64{},
+		},
+
+		{
+			"cnf problem",
+			`p cnf 1 1
+1 0
+`,
+			true,
+			nil,
+			nil,
+			[3]uint64{},
+		},
+
+		{
+			"hard in old format",
+			`p wcnf 1 1 10
+h 1 0
+`,
+			true,
+			nil,
+			nil,
+			[3]uint64{},
+		},
+
+		{
+			"negative weight",
+			`-1 1 0
+`,
+			true,
+			nil,
+			nil,
+			[3]uint64{},
+		},
+
+		{
+			"invalid literal",
+			`h 1 x 0
+`,
+			true,
+			nil,
+			nil,
+			[3]uint64{},
+		},
+
+		{
+			"unterminated clause",
+			`h 1 0
+3 -1
+`,
+			true,
+			nil,
+			nil,
+			[3]uint64{},
+		},
+
+		{
+			"unterminated weight",
+			`h 1 0
+3
+`,
+			true,
+			nil,
+			nil,
+			[3]uint64{},
+		},
+
+		{
+			"too few clauses",
+			`p wcnf 2 3 10
+10 1 0
+3 -2 0
+`,
+			true,
+			nil,
+			nil,
+			[3]uint64{},
+		},
+
+		{
+			"too many clauses",
+			`p wcnf 2 1 10
+10 1 0
+3 -2 0
+`,
+			true,
+			nil,
+			nil,
+			[3]uint64{},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d-%s", i, tc.Name), func(t *testing.T) {
+			result, err := ParseWCNF(strings.NewReader(tc.Input))
+			if (err != nil) != tc.Err {
+				t.Fatalf("bad: %s", err)
+			}
+			if err != nil {
+				return
+			}
+
+			header := [3]uint64{uint64(result.Variables), uint64(result.Clauses), result.Top}
+			if header != tc.Header {
+				t.Fatalf("bad: %v", header)
+			}
+
+			if actual := result.Hard.Int(); !reflect.DeepEqual(actual, tc.Hard) && len(actual)+len(tc.Hard) > 0 {
+				t.Fatalf("bad: %#v", actual)
+			}
+
+			if actual := softInts(result.Soft); !reflect.DeepEqual(actual, tc.Soft) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+}
+
+func TestParseWCNF_errorPosition(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Input    string
+		Expected string
+	}{
+		{
+			"unterminated clause",
+			"h 1 0\n\n  3 -1\n2\n",
+			"line 3, column 3: clause is not terminated by 0",
+		},
+
+		{
+			"clause count",
+			"p wcnf 2 3 10\n10 1 0\n3 -2 0\n",
+			"line 1, column 10: problem line declares 3 clauses but 2 were found",
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d-%s", i, tc.Name), func(t *testing.T) {
+			_, err := ParseWCNF(strings.NewReader(tc.Input))
+			perr, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("bad: %#v", err)
+			}
+			if perr.Error() != tc.Expected {
+				t.Fatalf("bad: %s", perr)
+			}
+		})
+	}
+}
+
+func TestWriteWCNF(t *testing.T) {
+	problem := &WeightedProblem{
+		Hard: cnf.NewFormulaFromInts([][]int{
+			[]int{1, -2},
+			[]int{},
+		}),
+		Soft: []WeightedClause{
+			{Clause: cnf.NewClauseFromInts([]int{3}), Weight: 4},
+			{Clause: cnf.NewClauseFromInts([]int{-1, 2}), Weight: 1},
+		},
+	}
+
+	cases := []struct {
+		Name   string
+		Top    uint64
+		Output string
+	}{
+		{
+			"new format",
+			0,
+			`h 1 -2 0
+h 0
+4 3 0
+1 -1 2 0
+`,
+		},
+
+		{
+			"old format",
+			5,
+			`p wcnf 3 4 5
+5 1 -2 0
+5 0
+4 3 0
+1 -1 2 0
+`,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d-%s", i, tc.Name), func(t *testing.T) {
+			p := *problem
+			p.Top = tc.Top
+
+			var buf bytes.Buffer
+			if err := WriteWCNF(&buf, &p); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if buf.String() != tc.Output {
+				t.Fatalf("bad: %s", buf.String())
+			}
+
+			// Parsing the output should give the same clauses
+			result, err := ParseWCNF(&buf)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if !reflect.DeepEqual(result.Hard.Int(), p.Hard.Int()) {
+				t.Fatalf("bad: %#v", result.Hard.Int())
+			}
+			if !reflect.DeepEqual(softInts(result.Soft), softInts(p.Soft)) {
+				t.Fatalf("bad: %#v", softInts(result.Soft))
+			}
+		})
+	}
+}
+
+func TestWriteWCNF_topTooSmall(t *testing.T) {
+	problem := &WeightedProblem{
+		Top: 4,
+		Soft: []WeightedClause{
+			{Clause: cnf.NewClauseFromInts([]int{3}), Weight: 4},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteWCNF(&buf, problem); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+// softInts returns the soft clauses as ints with the weight first.
+func softInts(soft []WeightedClause) [][]int {
+	var result [][]int
+	for _, c := range soft {
+		result = append(result, append([]int{int(c.Weight)}, c.Clause.Int()...))
+	}
+
+	return result
+}
+
+func TestWCNF_comments(t *testing.T) {
+	input := `c Foo
+c
+h 1 0
+c Bar
+3 -1 0
+`
+	p, err := ParseWCNF(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{"Foo", ""}
+	if !reflect.DeepEqual(p.Comments, expected) {
+		t.Fatalf("bad: %#v", p.Comments)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteWCNF(&buf, p); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	output := `c Foo
+c
+h 1 0
+3 -1 0
+`
+	if buf.String() != output {
+		t.Fatalf("bad: %s", buf.String())
+	}
+}
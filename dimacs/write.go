@@ -0,0 +1,92 @@
+package dimacs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+// Write writes the problem to w in DIMACS CNF form.
+//
+// The problem line is computed from the formula rather than taken from
+// Variables and Clauses, so the output is always consistent. Variables is
+// only used if it is larger than every variable in the formula, which
+// declares variables that aren't in any clause. The comments are written
+// before the problem line.
+func Write(w io.Writer, p *Problem) error {
+	vars := maxVar(p.Formula)
+	if p.Variables > vars {
+		vars = p.Variables
+	}
+
+	bw := bufio.NewWriter(w)
+	writeComments(bw, p.Comments)
+	fmt.Fprintf(bw, "p cnf %d %d\n", vars, len(p.Formula))
+
+	var buf []byte
+	for _, c := range p.Formula {
+		buf = appendClause(buf[:0], c)
+		bw.Write(buf)
+	}
+
+	return bw.Flush()
+}
+
+// WriteFormula writes the formula to w in DIMACS CNF form.
+func WriteFormula(w io.Writer, f cnf.Formula) error {
+	return Write(w, &Problem{Formula: f})
+}
+
+// writeComments writes every comment as a comment line. A comment with
+// line breaks is written as multiple comment lines.
+func writeComments(w *bufio.Writer, comments []string) {
+	for _, comment := range comments {
+		start := 0
+		for i := 0; i <= len(comment); i++ {
+			if i < len(comment) && comment[i] != '\n' {
+				continue
+			}
+
+			line := comment[start:i]
+			start = i + 1
+			if line == "" {
+				w.WriteString("c\n")
+				continue
+			}
+
+			w.WriteString("c ")
+			w.WriteString(line)
+			w.WriteByte('\n')
+		}
+	}
+}
+
+// appendClause appends the literals of the clause followed by the
+// terminating zero and a newline to buf.
+func appendClause(buf []byte, c cnf.Clause) []byte {
+	for _, l := range c {
+		buf = strconv.AppendInt(buf, int64(l.Int()), 10)
+		buf = append(buf, ' ')
+	}
+
+	return append(buf, "0\n"...)
+}
+
+// maxVar returns the largest variable in the clauses.
+func maxVar(clauses ...cnf.Formula) int {
+	result := 0
+	for _, f := range clauses {
+		for _, c := range f {
+			for _, l := range c {
+				if v := l.Var(); v > result {
+					result = v
+				}
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,180 @@
+package dimacs
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+func TestWrite(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Problem *Problem
+		Output  string
+	}{
+		{
+			"empty",
+			&Problem{},
+			"p cnf 0 0\n",
+		},
+
+		{
+			"basic",
+			&Problem{
+				Formula: cnf.NewFormulaFromInts([][]int{
+					[]int{1, -3},
+					[]int{2, 1, 3},
+					[]int{},
+				}),
+			},
+			`p cnf 3 3
+1 -3 0
+2 1 3 0
+0
+`,
+		},
+
+		{
+			"header from formula",
+			&Problem{
+				Variables: 1,
+				Clauses:   7,
+				Formula: cnf.NewFormulaFromInts([][]int{
+					[]int{-4},
+				}),
+			},
+			`p cnf 4 1
+-4 0
+`,
+		},
+
+		{
+			"extra variables",
+			&Problem{
+				Variables: 10,
+				Formula: cnf.NewFormulaFromInts([][]int{
+					[]int{-4},
+				}),
+			},
+			`p cnf 10 1
+-4 0
+`,
+		},
+
+		{
+			"comments",
+			&Problem{
+				Comments: []string{"Foo", "", "Bar\nBaz"},
+				Formula: cnf.NewFormulaFromInts([][]int{
+					[]int{1},
+				}),
+			},
+			`c Foo
+c
+c Bar
+c Baz
+p cnf 1 1
+1 0
+`,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d-%s", i, tc.Name), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, tc.Problem); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if buf.String() != tc.Output {
+				t.Fatalf("bad: %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestWriteFormula(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteFormula(&buf, cnf.NewFormulaFromInts([][]int{
+		[]int{1, 2},
+		[]int{-2},
+	}))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := `p cnf 2 2
+1 2 0
+-2 0
+`
+	if buf.String() != expected {
+		t.Fatalf("bad: %s", buf.String())
+	}
+}
+
+func TestWrite_roundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	for i := 0; i < 100; i++ {
+		ints := make([][]int, 1+r.Intn(20))
+		for j := range ints {
+			ints[j] = []int{}
+			for k := r.Intn(5); k > 0; k-- {
+				l := 1 + r.Intn(30)
+				if r.Intn(2) == 0 {
+					l = -l
+				}
+
+				ints[j] = append(ints[j], l)
+			}
+		}
+
+		expected := &Problem{
+			Variables: 30,
+			Clauses:   len(ints),
+			Formula:   cnf.NewFormulaFromInts(ints),
+			Comments:  []string{"Foo", fmt.Sprintf("%d", i)},
+		}
+
+		var buf bytes.Buffer
+		if err := Write(&buf, expected); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		actual, err := Parse(&buf)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		// Compare the formulas as ints since empty clauses are nil
+		if !reflect.DeepEqual(actual.Formula.Int(), ints) {
+			t.Fatalf("bad %d: %#v", i, actual.Formula.Int())
+		}
+
+		actual.Formula, expected.Formula = nil, nil
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("bad %d: %#v", i, actual)
+		}
+	}
+}
+
+func TestParse_comments(t *testing.T) {
+	input := `c Foo
+c
+c  Bar
+p cnf 1 1
+1 0
+`
+	p, err := Parse(bytes.NewBufferString(input))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{"Foo", "", " Bar"}
+	if !reflect.DeepEqual(p.Comments, expected) {
+		t.Fatalf("bad: %#v", p.Comments)
+	}
+}
@@ -0,0 +1,448 @@
+package xz
+
+import (
+	"io"
+)
+
+// This file contains the LZMA decoder used by LZMA2 chunks. It follows
+// the reference decoder in the LZMA SDK (LzmaSpec.cpp).
+
+const (
+	numStates       = 12
+	posStatesMax    = 1 << 4
+	matchMinLen     = 2
+	endPosModel     = 14
+	numFullDists    = 1 << (endPosModel >> 1)
+	numAlignBits    = 4
+	numLenToPos     = 4
+	probInit        = 1 << 10
+	numBitModelBits = 11
+	numMoveBits     = 5
+	rangeTop        = 1 << 24
+)
+
+// prob is the probability of a bit being zero in units of
+// 1/(1<<numBitModelBits).
+type prob uint16
+
+// rangeDecoder is the range decoder (arithmetic decoder) for the
+// compressed bits of a single LZMA2 chunk.
+type rangeDecoder struct {
+	r         io.ByteReader
+	remaining int // bytes of the chunk that haven't been read
+	rng       uint32
+	code      uint32
+	err       error
+}
+
+// init starts decoding a chunk of size compressed bytes.
+func (rc *rangeDecoder) init(r io.ByteReader, size int) error {
+	rc.r = r
+	rc.remaining = size
+	rc.rng = 0xFFFFFFFF
+	rc.code = 0
+	rc.err = nil
+
+	if rc.readByte() != 0 {
+		return errCorrupt
+	}
+	for i := 0; i < 4; i++ {
+		rc.code = rc.code<<8 | uint32(rc.readByte())
+	}
+	if rc.code == rc.rng {
+		return errCorrupt
+	}
+
+	return rc.err
+}
+
+// readByte returns the next byte of the chunk. Errors are saved in err
+// and checked once per symbol so that decoding bits stays simple.
+func (rc *rangeDecoder) readByte() byte {
+	if rc.remaining == 0 {
+		if rc.err == nil {
+			rc.err = errCorrupt
+		}
+
+		return 0
+	}
+
+	b, err := rc.r.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		if rc.err == nil {
+			rc.err = err
+		}
+
+		return 0
+	}
+
+	rc.remaining--
+	return b
+}
+
+// finished returns true if the chunk was decoded exactly.
+func (rc *rangeDecoder) finished() bool {
+	return rc.remaining == 0 && rc.code == 0
+}
+
+func (rc *rangeDecoder) normalize() {
+	if rc.rng < rangeTop {
+		rc.rng <<= 8
+		rc.code = rc.code<<8 | uint32(rc.readByte())
+	}
+}
+
+// bit decodes a single bit with the probability p and updates p.
+func (rc *rangeDecoder) bit(p *prob) uint32 {
+	bound := (rc.rng >> numBitModelBits) * uint32(*p)
+	var result uint32
+	if rc.code < bound {
+		rc.rng = bound
+		*p += ((1 << numBitModelBits) - *p) >> numMoveBits
+	} else {
+		rc.rng -= bound
+		rc.code -= bound
+		*p -= *p >> numMoveBits
+		result = 1
+	}
+
+	rc.normalize()
+	return result
+}
+
+// direct decodes n bits with a fixed probability of one half.
+func (rc *rangeDecoder) direct(n uint) uint32 {
+	var result uint32
+	for ; n > 0; n-- {
+		rc.rng >>= 1
+		rc.code -= rc.rng
+		t := 0 - (rc.code >> 31)
+		rc.code += rc.rng & t
+		if rc.code == rc.rng && rc.err == nil {
+			rc.err = errCorrupt
+		}
+
+		rc.normalize()
+		result = result<<1 + t + 1
+	}
+
+	return result
+}
+
+// bitTree decodes n bits, most significant first, with the
+// probabilities in probs which has 1<<n entries.
+func (rc *rangeDecoder) bitTree(probs []prob, n uint) uint32 {
+	m := uint32(1)
+	for i := uint(0); i < n; i++ {
+		m = m<<1 + rc.bit(&probs[m])
+	}
+
+	return m - 1<<n
+}
+
+// bitTreeReverse decodes n bits, least significant first, with the
+// probabilities in probs which has 1<<n entries.
+func (rc *rangeDecoder) bitTreeReverse(probs []prob, n uint) uint32 {
+	m := uint32(1)
+	var result uint32
+	for i := uint(0); i < n; i++ {
+		b := rc.bit(&probs[m])
+		m = m<<1 + b
+		result |= b << i
+	}
+
+	return result
+}
+
+// lenDecoder decodes match lengths.
+type lenDecoder struct {
+	choice  prob
+	choice2 prob
+	low     [posStatesMax][1 << 3]prob
+	mid     [posStatesMax][1 << 3]prob
+	high    [1 << 8]prob
+}
+
+func (d *lenDecoder) reset() {
+	d.choice = probInit
+	d.choice2 = probInit
+	resetProbs(d.high[:])
+	for i := range d.low {
+		resetProbs(d.low[i][:])
+		resetProbs(d.mid[i][:])
+	}
+}
+
+// decode returns the match length minus matchMinLen.
+func (d *lenDecoder) decode(rc *rangeDecoder, posState uint32) uint32 {
+	if rc.bit(&d.choice) == 0 {
+		return rc.bitTree(d.low[posState][:], 3)
+	}
+	if rc.bit(&d.choice2) == 0 {
+		return 8 + rc.bitTree(d.mid[posState][:], 3)
+	}
+
+	return 16 + rc.bitTree(d.high[:], 8)
+}
+
+// lzmaDecoder is the state of the LZMA decoder. The state is kept across
+// LZMA2 chunks unless a chunk resets it.
+type lzmaDecoder struct {
+	rc rangeDecoder
+
+	lc, lp, pb uint
+	state      uint32
+	rep        [4]uint32
+
+	// matchLen is the length of the match that is still being copied. A
+	// match may be split over multiple calls to decode.
+	matchLen int
+
+	literal    []prob
+	isMatch    [numStates << 4]prob
+	isRep      [numStates]prob
+	isRepG0    [numStates]prob
+	isRepG1    [numStates]prob
+	isRepG2    [numStates]prob
+	isRep0Long [numStates << 4]prob
+	posSlot    [numLenToPos][1 << 6]prob
+	posDecoder [1 + numFullDists - endPosModel]prob
+	align      [1 << numAlignBits]prob
+	lenDec     lenDecoder
+	repLenDec  lenDecoder
+}
+
+// setProps sets the lc, lp and pb properties from the properties byte
+// of an LZMA2 chunk.
+func (d *lzmaDecoder) setProps(b byte) error {
+	if b >= 9*5*5 {
+		return errCorrupt
+	}
+
+	d.lc = uint(b % 9)
+	b /= 9
+	d.lp = uint(b % 5)
+	d.pb = uint(b / 5)
+	if d.lc+d.lp > 4 {
+		return errCorrupt
+	}
+
+	return nil
+}
+
+// reset resets the state and every probability.
+func (d *lzmaDecoder) reset() {
+	d.state = 0
+	d.rep = [4]uint32{}
+	d.matchLen = 0
+
+	n := 0x300 << (d.lc + d.lp)
+	if cap(d.literal) < n {
+		d.literal = make([]prob, n)
+	}
+	d.literal = d.literal[:n]
+	resetProbs(d.literal)
+
+	resetProbs(d.isMatch[:])
+	resetProbs(d.isRep[:])
+	resetProbs(d.isRepG0[:])
+	resetProbs(d.isRepG1[:])
+	resetProbs(d.isRepG2[:])
+	resetProbs(d.isRep0Long[:])
+	for i := range d.posSlot {
+		resetProbs(d.posSlot[i][:])
+	}
+	resetProbs(d.posDecoder[:])
+	resetProbs(d.align[:])
+	d.lenDec.reset()
+	d.repLenDec.reset()
+}
+
+// decode decodes exactly len(out) bytes into out and the window.
+func (d *lzmaDecoder) decode(w *window, out []byte) error {
+	rc := &d.rc
+	n := 0
+	for n < len(out) {
+		// Finish copying the current match first
+		if d.matchLen > 0 {
+			b := w.get(int(d.rep[0]) + 1)
+			w.put(b)
+			out[n] = b
+			n++
+			d.matchLen--
+			continue
+		}
+
+		if rc.err != nil {
+			return rc.err
+		}
+
+		posState := uint32(w.total) & (1<<d.pb - 1)
+		if rc.bit(&d.isMatch[d.state<<4+posState]) == 0 {
+			// After a match the byte at rep0 is used as context
+			if d.state >= 7 && int64(d.rep[0]) >= int64(w.filled()) {
+				return errCorrupt
+			}
+
+			b := d.decodeLiteral(w)
+			w.put(b)
+			out[n] = b
+			n++
+
+			switch {
+			case d.state < 4:
+				d.state = 0
+			case d.state < 10:
+				d.state -= 3
+			default:
+				d.state -= 6
+			}
+
+			continue
+		}
+
+		var length uint32
+		if rc.bit(&d.isRep[d.state]) != 0 {
+			if w.filled() == 0 {
+				return errCorrupt
+			}
+
+			if rc.bit(&d.isRepG0[d.state]) == 0 {
+				if rc.bit(&d.isRep0Long[d.state<<4+posState]) == 0 {
+					// Short rep: a single byte at rep0
+					if int64(d.rep[0]) >= int64(w.filled()) {
+						return errCorrupt
+					}
+
+					if d.state < 7 {
+						d.state = 9
+					} else {
+						d.state = 11
+					}
+
+					b := w.get(int(d.rep[0]) + 1)
+					w.put(b)
+					out[n] = b
+					n++
+					continue
+				}
+			} else {
+				var dist uint32
+				if rc.bit(&d.isRepG1[d.state]) == 0 {
+					dist = d.rep[1]
+				} else {
+					if rc.bit(&d.isRepG2[d.state]) == 0 {
+						dist = d.rep[2]
+					} else {
+						dist = d.rep[3]
+						d.rep[3] = d.rep[2]
+					}
+
+					d.rep[2] = d.rep[1]
+				}
+
+				d.rep[1] = d.rep[0]
+				d.rep[0] = dist
+			}
+
+			length = d.repLenDec.decode(rc, posState)
+			if d.state < 7 {
+				d.state = 8
+			} else {
+				d.state = 11
+			}
+		} else {
+			d.rep[3] = d.rep[2]
+			d.rep[2] = d.rep[1]
+			d.rep[1] = d.rep[0]
+			length = d.lenDec.decode(rc, posState)
+			if d.state < 7 {
+				d.state = 7
+			} else {
+				d.state = 10
+			}
+
+			// LZMA2 doesn't allow the end marker, so it is treated like
+			// any other distance that is out of range.
+			d.rep[0] = d.decodeDistance(length)
+		}
+
+		if rc.err != nil {
+			return rc.err
+		}
+		if int64(d.rep[0]) >= int64(w.filled()) {
+			return errCorrupt
+		}
+
+		d.matchLen = int(length) + matchMinLen
+	}
+
+	return rc.err
+}
+
+// decodeLiteral decodes a single literal byte.
+func (d *lzmaDecoder) decodeLiteral(w *window) byte {
+	rc := &d.rc
+	var prev uint32
+	if w.filled() > 0 {
+		prev = uint32(w.get(1))
+	}
+
+	litState := (uint32(w.total)&(1<<d.lp-1))<<d.lc + prev>>(8-d.lc)
+	probs := d.literal[0x300*litState : 0x300*(litState+1)]
+
+	symbol := uint32(1)
+	if d.state >= 7 {
+		// After a match the literal is likely to match the byte at rep0,
+		// which is used as context until the first bit that differs.
+		match := uint32(w.get(int(d.rep[0]) + 1))
+		for symbol < 0x100 {
+			matchBit := (match >> 7) & 1
+			match <<= 1
+			b := rc.bit(&probs[(1+matchBit)<<8+symbol])
+			symbol = symbol<<1 | b
+			if matchBit != b {
+				break
+			}
+		}
+	}
+
+	for symbol < 0x100 {
+		symbol = symbol<<1 | rc.bit(&probs[symbol])
+	}
+
+	return byte(symbol)
+}
+
+// decodeDistance decodes the distance of a match of the given length,
+// minus matchMinLen.
+func (d *lzmaDecoder) decodeDistance(length uint32) uint32 {
+	rc := &d.rc
+	lenState := length
+	if lenState > numLenToPos-1 {
+		lenState = numLenToPos - 1
+	}
+
+	posSlot := rc.bitTree(d.posSlot[lenState][:], 6)
+	if posSlot < 4 {
+		return posSlot
+	}
+
+	numDirectBits := uint(posSlot>>1) - 1
+	dist := (2 | posSlot&1) << numDirectBits
+	if posSlot < endPosModel {
+		return dist + rc.bitTreeReverse(d.posDecoder[dist-posSlot:], numDirectBits)
+	}
+
+	dist += rc.direct(numDirectBits-numAlignBits) << numAlignBits
+	return dist + rc.bitTreeReverse(d.align[:], numAlignBits)
+}
+
+func resetProbs(probs []prob) {
+	for i := range probs {
+		probs[i] = probInit
+	}
+}
@@ -0,0 +1,240 @@
+package xz
+
+import (
+	"io"
+)
+
+// window is the sliding window (dictionary) of the decompressed data that
+// matches are copied from. The buffer grows as needed up to the size of
+// the dictionary so small inputs don't allocate a large dictionary.
+type window struct {
+	buf   []byte
+	size  int    // size of the dictionary
+	pos   int    // next position to write in buf
+	full  bool   // true if buf has wrapped around
+	total uint64 // bytes written since the last reset
+}
+
+func (w *window) reset() {
+	w.buf = w.buf[:0]
+	w.pos = 0
+	w.full = false
+	w.total = 0
+}
+
+func (w *window) put(b byte) {
+	if len(w.buf) < w.size {
+		w.buf = append(w.buf, b)
+	} else {
+		w.buf[w.pos] = b
+	}
+
+	w.pos++
+	if w.pos == w.size {
+		w.pos = 0
+		w.full = true
+	}
+
+	w.total++
+}
+
+// get returns the byte dist bytes before the next position, so a dist of
+// 1 is the last byte written. dist must be at most filled.
+func (w *window) get(dist int) byte {
+	i := w.pos - dist
+	if i < 0 {
+		i += len(w.buf)
+	}
+
+	return w.buf[i]
+}
+
+// filled returns the number of bytes available to get.
+func (w *window) filled() int {
+	if w.full {
+		return len(w.buf)
+	}
+
+	return w.pos
+}
+
+// lzma2Reader decompresses LZMA2 data, which is a sequence of chunks
+// that are either stored uncompressed or compressed with LZMA.
+type lzma2Reader struct {
+	r   io.ByteReader
+	win window
+	lz  lzmaDecoder
+
+	// remaining is the number of bytes of the current chunk that haven't
+	// been read and compressed is true if the chunk uses LZMA.
+	remaining  int
+	compressed bool
+
+	needDictReset bool
+	needProps     bool
+	eof           bool
+}
+
+// newLZMA2Reader returns a reader that decompresses LZMA2 data from r
+// with the given dictionary size.
+func newLZMA2Reader(r io.ByteReader, dictSize int) *lzma2Reader {
+	return &lzma2Reader{
+		r:             r,
+		win:           window{size: dictSize},
+		needDictReset: true,
+		needProps:     true,
+	}
+}
+
+func (z *lzma2Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if z.remaining == 0 {
+			if z.eof {
+				return n, io.EOF
+			}
+
+			if err := z.nextChunk(); err != nil {
+				return n, err
+			}
+
+			continue
+		}
+
+		m := len(p) - n
+		if m > z.remaining {
+			m = z.remaining
+		}
+
+		out := p[n : n+m]
+		if z.compressed {
+			if err := z.lz.decode(&z.win, out); err != nil {
+				return n, err
+			}
+		} else {
+			for i := range out {
+				b, err := z.r.ReadByte()
+				if err != nil {
+					return n + i, unexpected(err)
+				}
+
+				z.win.put(b)
+				out[i] = b
+			}
+		}
+
+		n += m
+		z.remaining -= m
+	}
+
+	return n, nil
+}
+
+// nextChunk finishes the current chunk and reads the header of the next.
+func (z *lzma2Reader) nextChunk() error {
+	if z.compressed && (z.lz.matchLen > 0 || !z.lz.rc.finished()) {
+		return errCorrupt
+	}
+
+	control, err := z.r.ReadByte()
+	if err != nil {
+		return unexpected(err)
+	}
+
+	if control == 0x00 {
+		z.eof = true
+		z.compressed = false
+		return nil
+	}
+
+	// Uncompressed chunks, optionally resetting the dictionary
+	if control == 0x01 || control == 0x02 {
+		// The LZMA state may refer to data in the old dictionary, so the
+		// next LZMA chunk must reset the state with new properties.
+		if control == 0x01 {
+			z.win.reset()
+			z.needDictReset = false
+			z.needProps = true
+		} else if z.needDictReset {
+			return errCorrupt
+		}
+
+		size, err := z.readUint16()
+		if err != nil {
+			return err
+		}
+
+		z.remaining = size + 1
+		z.compressed = false
+		return nil
+	}
+
+	if control < 0x80 {
+		return errCorrupt
+	}
+
+	// LZMA chunk. Bits 5 and 6 are the reset: 0 is none, 1 resets the
+	// state, 2 also sets new properties and 3 also resets the dictionary.
+	reset := (control >> 5) & 3
+	if reset == 3 {
+		z.win.reset()
+		z.needDictReset = false
+	} else if z.needDictReset {
+		return errCorrupt
+	}
+
+	unpacked, err := z.readUint16()
+	if err != nil {
+		return err
+	}
+	packed, err := z.readUint16()
+	if err != nil {
+		return err
+	}
+
+	if reset >= 2 {
+		props, err := z.r.ReadByte()
+		if err != nil {
+			return unexpected(err)
+		}
+		if err := z.lz.setProps(props); err != nil {
+			return err
+		}
+
+		z.needProps = false
+	} else if z.needProps {
+		return errCorrupt
+	}
+
+	if reset >= 1 {
+		z.lz.reset()
+	}
+
+	z.remaining = int(control&0x1F)<<16 + unpacked + 1
+	z.compressed = true
+	return z.lz.rc.init(z.r, packed+1)
+}
+
+// readUint16 reads a big-endian 16-bit integer.
+func (z *lzma2Reader) readUint16() (int, error) {
+	hi, err := z.r.ReadByte()
+	if err != nil {
+		return 0, unexpected(err)
+	}
+	lo, err := z.r.ReadByte()
+	if err != nil {
+		return 0, unexpected(err)
+	}
+
+	return int(hi)<<8 | int(lo), nil
+}
+
+// unexpected converts io.EOF to io.ErrUnexpectedEOF for reads that must
+// succeed in valid data.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
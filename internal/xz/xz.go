@@ -0,0 +1,480 @@
+// Package xz decompresses the xz file format.
+//
+// Only the LZMA2 filter is supported, which is the only filter used by
+// xz unless other filters are explicitly requested. Every check type in
+// the format is supported and CRC32, CRC64 and SHA-256 checks are
+// verified. Concatenated streams and stream padding are supported.
+//
+// The format is described here: https://tukaani.org/xz/xz-file-format.txt
+package xz
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"hash/crc64"
+	"io"
+)
+
+// Magic is the magic bytes at the start of an xz file.
+var Magic = []byte{0xFD, '7', 'z', 'X', 'Z', 0x00}
+
+var footerMagic = []byte{'Y', 'Z'}
+
+var errCorrupt = errors.New("xz: corrupt data")
+
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+const (
+	checkNone   = 0x00
+	checkCRC32  = 0x01
+	checkCRC64  = 0x04
+	checkSHA256 = 0x0A
+
+	filterLZMA2 = 0x21
+)
+
+// Reader decompresses an xz file.
+type Reader struct {
+	r *countReader
+
+	flags []byte    // stream flags from the stream header
+	check hash.Hash // check of the uncompressed data, nil if not verified
+
+	// block is the current block, which is nil between blocks
+	block             *lzma2Reader
+	blockStart        int64 // offset of the block header
+	blockHeaderSize   int64
+	blockCompressed   int64 // compressed size from the header, or -1
+	blockUncompressed int64 // uncompressed size from the header, or -1
+	uncompressed      int64 // bytes read from the block so far
+
+	// records are the unpadded and uncompressed sizes of each block of
+	// the current stream to verify the index.
+	records []int64
+
+	err error
+}
+
+// NewReader creates a new Reader reading the xz data from r. The stream
+// header is read and verified before this returns.
+func NewReader(r io.Reader) (*Reader, error) {
+	z := &Reader{r: &countReader{r: bufio.NewReader(r)}}
+	if err := z.readStreamHeader(); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return nil, err
+	}
+
+	return z, nil
+}
+
+func (z *Reader) Read(p []byte) (int, error) {
+	if z.err != nil {
+		return 0, z.err
+	}
+
+	for {
+		if z.block == nil {
+			if z.err = z.nextBlock(); z.err != nil {
+				return 0, z.err
+			}
+
+			continue
+		}
+
+		n, err := z.block.Read(p)
+		z.uncompressed += int64(n)
+		if z.check != nil {
+			z.check.Write(p[:n])
+		}
+
+		if err == io.EOF {
+			err = z.finishBlock()
+			z.block = nil
+			if err == nil && n == 0 {
+				continue
+			}
+		}
+
+		z.err = err
+		return n, err
+	}
+}
+
+// readStreamHeader reads the stream header at the start of every stream.
+func (z *Reader) readStreamHeader() error {
+	var header [12]byte
+	if _, err := io.ReadFull(z.r, header[:]); err != nil {
+		return err
+	}
+
+	if !bytes.Equal(header[:6], Magic) {
+		return errors.New("xz: invalid header magic bytes")
+	}
+	if binary.LittleEndian.Uint32(header[8:]) != crc32.ChecksumIEEE(header[6:8]) {
+		return errCorrupt
+	}
+	if header[6] != 0 || header[7] > 0x0F {
+		return errors.New("xz: unsupported stream flags")
+	}
+
+	z.flags = append(z.flags[:0], header[6:8]...)
+	z.records = z.records[:0]
+	switch header[7] {
+	case checkNone:
+		z.check = nil
+	case checkCRC32:
+		z.check = crc32.NewIEEE()
+	case checkCRC64:
+		z.check = crc64.New(crc64Table)
+	case checkSHA256:
+		z.check = sha256.New()
+	default:
+		// Unknown checks are skipped without being verified
+		z.check = nil
+	}
+
+	return nil
+}
+
+// checkSize returns the size of the check for the current stream.
+func (z *Reader) checkSize() int64 {
+	id := z.flags[1]
+	if id == 0 {
+		return 0
+	}
+
+	return 4 << ((id - 1) / 3)
+}
+
+// nextBlock reads the next block header, or the index and the rest of
+// the stream if there are no more blocks. This returns io.EOF at the end
+// of the last stream.
+func (z *Reader) nextBlock() error {
+	start := z.r.n
+	size, err := z.r.ReadByte()
+	if err != nil {
+		return unexpected(err)
+	}
+
+	// A zero block header size is the start of the index
+	if size == 0 {
+		if err := z.readIndex(); err != nil {
+			return err
+		}
+
+		return z.nextStream()
+	}
+
+	header := make([]byte, (int(size)+1)*4)
+	header[0] = size
+	if _, err := io.ReadFull(z.r, header[1:]); err != nil {
+		return unexpected(err)
+	}
+
+	n := len(header) - 4
+	if binary.LittleEndian.Uint32(header[n:]) != crc32.ChecksumIEEE(header[:n]) {
+		return errCorrupt
+	}
+
+	flags := header[1]
+	if flags&0x3C != 0 {
+		return errors.New("xz: unsupported block flags")
+	}
+
+	buf := bytes.NewReader(header[2:n])
+	z.blockCompressed = -1
+	z.blockUncompressed = -1
+	if flags&0x40 != 0 {
+		v, err := readVarint(buf)
+		if err != nil || v == 0 {
+			return errCorrupt
+		}
+
+		z.blockCompressed = int64(v)
+	}
+	if flags&0x80 != 0 {
+		v, err := readVarint(buf)
+		if err != nil {
+			return errCorrupt
+		}
+
+		z.blockUncompressed = int64(v)
+	}
+
+	// Only a single LZMA2 filter is supported
+	if flags&0x03 != 0 {
+		return errors.New("xz: unsupported filter chain")
+	}
+
+	id, err := readVarint(buf)
+	if err != nil {
+		return errCorrupt
+	}
+	if id != filterLZMA2 {
+		return fmt.Errorf("xz: unsupported filter 0x%x", id)
+	}
+
+	propsSize, err := readVarint(buf)
+	if err != nil || propsSize != 1 {
+		return errCorrupt
+	}
+
+	props, err := buf.ReadByte()
+	if err != nil || props > 40 {
+		return errCorrupt
+	}
+
+	// The rest of the header is padding
+	for buf.Len() > 0 {
+		if b, _ := buf.ReadByte(); b != 0 {
+			return errCorrupt
+		}
+	}
+
+	z.blockStart = start
+	z.blockHeaderSize = int64(len(header))
+	z.uncompressed = 0
+	if z.check != nil {
+		z.check.Reset()
+	}
+
+	z.block = newLZMA2Reader(z.r, dictSize(props))
+	return nil
+}
+
+// finishBlock verifies the sizes and check of the block that was just
+// read to the end.
+func (z *Reader) finishBlock() error {
+	compressed := z.r.n - z.blockStart - z.blockHeaderSize
+	if z.blockCompressed >= 0 && compressed != z.blockCompressed {
+		return errCorrupt
+	}
+	if z.blockUncompressed >= 0 && z.uncompressed != z.blockUncompressed {
+		return errCorrupt
+	}
+
+	if err := z.readPadding(compressed); err != nil {
+		return err
+	}
+
+	check := make([]byte, z.checkSize())
+	if _, err := io.ReadFull(z.r, check); err != nil {
+		return unexpected(err)
+	}
+
+	if z.check != nil {
+		var sum []byte
+		switch h := z.check.(type) {
+		case hash.Hash32:
+			sum = make([]byte, 4)
+			binary.LittleEndian.PutUint32(sum, h.Sum32())
+		case hash.Hash64:
+			sum = make([]byte, 8)
+			binary.LittleEndian.PutUint64(sum, h.Sum64())
+		default:
+			sum = h.Sum(nil)
+		}
+
+		if !bytes.Equal(sum, check) {
+			return errors.New("xz: checksum mismatch")
+		}
+	}
+
+	unpadded := z.blockHeaderSize + compressed + int64(len(check))
+	z.records = append(z.records, unpadded, z.uncompressed)
+	return nil
+}
+
+// readIndex reads the index after the index indicator, verifying that it
+// matches the blocks that were read.
+func (z *Reader) readIndex() error {
+	start := z.r.n - 1
+	crc := crc32.NewIEEE()
+	crc.Write([]byte{0})
+	r := &hashReader{r: z.r, h: crc}
+
+	count, err := readVarint(r)
+	if err != nil {
+		return errCorrupt
+	}
+	if count != uint64(len(z.records)/2) {
+		return errCorrupt
+	}
+
+	for _, expected := range z.records {
+		v, err := readVarint(r)
+		if err != nil || int64(v) != expected {
+			return errCorrupt
+		}
+	}
+
+	// The index is padded to a multiple of four bytes
+	for size := z.r.n - start; size%4 != 0; size++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return unexpected(err)
+		}
+		if b != 0 {
+			return errCorrupt
+		}
+	}
+
+	// The backward size in the footer includes the CRC32 of the index
+	size := z.r.n - start + 4
+
+	var footer [16]byte
+	if _, err := io.ReadFull(z.r, footer[:]); err != nil {
+		return unexpected(err)
+	}
+
+	// The CRC32 of the index is followed by the stream footer
+	if binary.LittleEndian.Uint32(footer[:4]) != crc.Sum32() {
+		return errCorrupt
+	}
+
+	footer2 := footer[4:]
+	if binary.LittleEndian.Uint32(footer2) != crc32.ChecksumIEEE(footer2[4:10]) ||
+		!bytes.Equal(footer2[10:], footerMagic) ||
+		!bytes.Equal(footer2[8:10], z.flags) ||
+		(int64(binary.LittleEndian.Uint32(footer2[4:]))+1)*4 != size {
+		return errCorrupt
+	}
+
+	return nil
+}
+
+// nextStream skips stream padding and reads the header of the next
+// stream. This returns io.EOF if there are no more streams.
+func (z *Reader) nextStream() error {
+	var buf [4]byte
+	for {
+		n, err := io.ReadFull(z.r, buf[:])
+		if err == io.EOF {
+			return io.EOF
+		}
+		if err != nil {
+			// Padding is a multiple of four bytes
+			if n > 0 {
+				return errCorrupt
+			}
+
+			return err
+		}
+
+		if buf != [4]byte{} {
+			break
+		}
+	}
+
+	// The padding is followed by another stream
+	z.r.unread = append(z.r.unread[:0], buf[:]...)
+	return unexpected(z.readStreamHeader())
+}
+
+// readPadding reads the null bytes that pad size bytes to a multiple of
+// four bytes.
+func (z *Reader) readPadding(size int64) error {
+	for ; size%4 != 0; size++ {
+		b, err := z.r.ReadByte()
+		if err != nil {
+			return unexpected(err)
+		}
+		if b != 0 {
+			return errCorrupt
+		}
+	}
+
+	return nil
+}
+
+// dictSize returns the dictionary size from the LZMA2 filter properties.
+func dictSize(props byte) int {
+	if props == 40 {
+		return 0xFFFFFFFF
+	}
+
+	return (2 | int(props)&1) << (props/2 + 11)
+}
+
+// readVarint reads a variable length integer used by the xz format: up
+// to nine bytes with seven bits each, least significant first.
+func readVarint(r io.ByteReader) (uint64, error) {
+	var result uint64
+	for i := uint(0); i < 9; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, unexpected(err)
+		}
+
+		result |= uint64(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			// Only the first byte may be zero
+			if b == 0 && i > 0 {
+				return 0, errCorrupt
+			}
+
+			return result, nil
+		}
+	}
+
+	return 0, errCorrupt
+}
+
+// countReader counts the bytes read from r. Bytes in unread are returned
+// before reading r and aren't counted again.
+type countReader struct {
+	r      *bufio.Reader
+	n      int64
+	unread []byte
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	if len(c.unread) > 0 {
+		n := copy(p, c.unread)
+		c.unread = c.unread[n:]
+		return n, nil
+	}
+
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countReader) ReadByte() (byte, error) {
+	if len(c.unread) > 0 {
+		b := c.unread[0]
+		c.unread = c.unread[1:]
+		return b, nil
+	}
+
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+
+	return b, err
+}
+
+// hashReader writes every byte read from r to h.
+type hashReader struct {
+	r io.ByteReader
+	h hash.Hash
+}
+
+func (r *hashReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.h.Write([]byte{b})
+	}
+
+	return b, err
+}
@@ -0,0 +1,177 @@
+package xz
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	aim, err := ioutil.ReadFile(filepath.Join(
+		"..", "..", "testdata", "satlib", "file-dimacs-aim", "aim-200-6_0-yes1-1.cnf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	random, err := ioutil.ReadFile(filepath.Join("testdata", "random.bin"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := []struct {
+		File     string
+		Expected []byte
+	}{
+		{"aim.xz", aim},
+		{"aim-crc32.xz", aim},
+		{"aim-sha256.xz", aim},
+		{"aim-none.xz", aim},
+		{"aim-blocks.xz", aim},
+		{"aim-props.xz", aim},
+		{"aim-concat.xz", append(append([]byte(nil), aim...), aim...)},
+		{"aim-repeat.xz", bytes.Repeat(aim, 200)},
+		{"random.xz", random},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.File, func(t *testing.T) {
+			actual := decompress(t, tc.File)
+			if !bytes.Equal(actual, tc.Expected) {
+				t.Fatalf("bad: %d bytes", len(actual))
+			}
+		})
+	}
+}
+
+func TestReader_smallReads(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "aim-blocks.xz"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Reading a byte at a time splits matches across reads
+	var buf bytes.Buffer
+	p := make([]byte, 1)
+	for {
+		n, err := r.Read(p)
+		buf.Write(p[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	if expected := decompress(t, "aim.xz"); !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("bad: %d bytes", buf.Len())
+	}
+}
+
+func TestReader_corrupt(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "aim.xz"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Flipping any bit must be detected by one of the checks, and
+	// truncating the data must be an error.
+	for i := 0; i < len(data); i += 7 {
+		corrupt := append([]byte(nil), data...)
+		corrupt[i] ^= 0x10
+		if _, err := readAll(corrupt); err == nil {
+			t.Fatalf("no error flipping byte %d", i)
+		}
+	}
+
+	for _, n := range []int{0, 5, 12, 100, len(data) - 1} {
+		if _, err := readAll(data[:n]); err == nil {
+			t.Fatalf("no error truncating to %d", n)
+		}
+	}
+}
+
+func decompress(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	result, err := readAll(data)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return result
+}
+
+func readAll(data []byte) ([]byte, error) {
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(r)
+}
+
+func TestLZMA2Reader_resetWithoutProps(t *testing.T) {
+	// An LZMA chunk, then an uncompressed chunk that resets the dictionary,
+	// then an LZMA chunk that continues the state of the first chunk. The
+	// state of the first chunk refers to data that is no longer in the
+	// dictionary, so the last chunk must be rejected.
+	lzma := []byte{
+		0x00, 0x30, 0x98, 0x88, 0x98, 0x3e, 0xcb, 0xe2, 0x6f, 0x3a,
+		0xae, 0x3e, 0xd2, 0xdc, 0x1c, 0x98, 0xd9, 0x4c, 0x00,
+	}
+
+	var data []byte
+	data = append(data, 0xE0, 0x00, 0x42, 0x00, 0x12, 0x5D)
+	data = append(data, lzma...)
+	data = append(data, 0x01, 0x00, 0x00, 'x')
+	data = append(data, 0x80, 0x00, 0x42, 0x00, 0x12)
+	data = append(data, lzma...)
+	data = append(data, 0x00)
+
+	r := newLZMA2Reader(bytes.NewReader(data), 1<<20)
+	if _, err := ioutil.ReadAll(r); err != errCorrupt {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Without the last chunk the data is valid
+	valid := append(data[:6+len(lzma)+4:6+len(lzma)+4], 0x00)
+	actual, err := ioutil.ReadAll(newLZMA2Reader(bytes.NewReader(valid), 1<<20))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if expected := append(bytes.Repeat([]byte("abcdefgh"), 8), "xyzx"...); !bytes.Equal(actual, expected) {
+		t.Fatalf("bad: %q", actual)
+	}
+}
+
+func TestLZMADecoder_repOutOfRange(t *testing.T) {
+	// After a match the next literal is decoded using the byte at rep0 as
+	// context, which must be in the window.
+	var d lzmaDecoder
+	d.setProps(0x5D)
+	d.reset()
+	d.state = 7
+	d.rep[0] = 10
+
+	var w window
+	w.size = 1 << 12
+	w.put('a')
+
+	if err := d.rc.init(bytes.NewReader(make([]byte, 16)), 16); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := d.decode(&w, make([]byte, 1)); err != errCorrupt {
+		t.Fatalf("err: %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package maxsat
+
+import (
+	"context"
+
+	"github.com/mitchellh/go-sat"
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+// coreSoft is a soft clause in the SAT solver for core-guided search. The
+// clause is added with a selector literal that is assumed false to enforce
+// the clause. Adding the selector as a unit clause disables the clause.
+type coreSoft struct {
+	lits   []cnf.Lit
+	weight uint64
+	sel    cnf.Lit
+}
+
+// solveCore solves the problem with stratified WPM1 core-guided search.
+func (s *Solver) solveCore(ctx context.Context, p *Problem) (*Solution, error) {
+	solver := s.newSolver(p)
+
+	var softs []*coreSoft
+	bySel := make(map[cnf.Lit]*coreSoft)
+	add := func(lits []cnf.Lit, weight uint64) {
+		soft := &coreSoft{lits: lits, weight: weight, sel: newLit(solver)}
+		addClause(solver, append(lits[:len(lits):len(lits)], soft.sel)...)
+		softs = append(softs, soft)
+		bySel[soft.sel.Neg()] = soft
+	}
+
+	for _, soft := range p.Soft {
+		if soft.Weight > 0 {
+			add(soft.Clause, soft.Weight)
+		}
+	}
+
+	// Only the soft clauses with a weight of at least the threshold are
+	// enforced. The threshold starts at the largest weight and is lowered
+	// each time the enforced clauses are satisfiable.
+	threshold := nextThreshold(softs, ^uint64(0))
+
+	var best *Solution
+	var lower uint64
+	var assumptions []cnf.Lit
+	for {
+		assumptions = assumptions[:0]
+		for _, soft := range softs {
+			if soft.weight > 0 && soft.weight >= threshold {
+				assumptions = append(assumptions, soft.sel.Neg())
+			}
+		}
+
+		switch solver.SolveContext(ctx, assumptions...) {
+		case sat.ResultUnknown:
+			return best, interrupted(ctx)
+
+		case sat.ResultSat:
+			best = s.solution(p, solver, best)
+
+			// The solution is optimal if every soft clause is enforced
+			// or if it reaches the lower bound.
+			threshold = nextThreshold(softs, threshold)
+			if threshold == 0 || best.Cost == lower {
+				return best, nil
+			}
+
+			continue
+		}
+
+		// Relaxing the clauses never makes the hard clauses unsatisfiable,
+		// so an empty core means that they were unsatisfiable to begin with.
+		failed := solver.FailedAssumptions()
+		if len(failed) == 0 {
+			return nil, ErrUnsatisfiable
+		}
+
+		core := make([]*coreSoft, len(failed))
+		wmin := ^uint64(0)
+		for i, l := range failed {
+			core[i] = bySel[l]
+			if w := core[i].weight; w < wmin {
+				wmin = w
+			}
+		}
+		lower += wmin
+
+		// A clause that is a core by itself can never be satisfied
+		if len(core) == 1 {
+			core[0].weight = 0
+			addClause(solver, core[0].sel)
+			continue
+		}
+
+		// Split every clause in the core into a copy with weight wmin that
+		// has a new relaxation literal and the rest of the weight, which
+		// stays in the original clause. Exactly one of the relaxation
+		// literals is true, so one clause in the core may be falsified.
+		relax := make([]cnf.Lit, len(core))
+		for i, soft := range core {
+			relax[i] = newLit(solver)
+			add(append(soft.lits[:len(soft.lits):len(soft.lits)], relax[i]), wmin)
+
+			soft.weight -= wmin
+			if soft.weight == 0 {
+				addClause(solver, soft.sel)
+			}
+		}
+
+		exactlyOne(solver, relax)
+	}
+}
+
+// nextThreshold returns the largest weight of a soft clause that is less
+// than threshold, or zero if there is none.
+func nextThreshold(softs []*coreSoft, threshold uint64) uint64 {
+	var result uint64
+	for _, soft := range softs {
+		if soft.weight < threshold && soft.weight > result {
+			result = soft.weight
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,108 @@
+package maxsat
+
+import (
+	"sort"
+
+	"github.com/mitchellh/go-sat"
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+// This file contains the encodings of cardinality and pseudo-Boolean
+// constraints into clauses.
+
+// totalizerOutput is an output of a generalized totalizer: lit is true if
+// the weighted sum of the inputs is at least value.
+type totalizerOutput struct {
+	value uint64
+	lit   cnf.Lit
+}
+
+// totalizer encodes the weighted sum of the literals lits with the
+// generalized totalizer encoding and returns the outputs sorted by value.
+// For every sum that the true inputs can add up to there is an output
+// that is implied by those inputs. Sums at or above limit all use the
+// output for limit.
+//
+// The sum can be limited to less than k by adding the negation of every
+// output with a value of at least k.
+func totalizer(s *sat.Solver, lits []cnf.Lit, weights []uint64, limit uint64) []totalizerOutput {
+	if len(lits) == 0 {
+		return nil
+	}
+
+	if len(lits) == 1 {
+		value := weights[0]
+		if value > limit {
+			value = limit
+		}
+
+		return []totalizerOutput{{value: value, lit: lits[0]}}
+	}
+
+	mid := len(lits) / 2
+	left := totalizer(s, lits[:mid], weights[:mid], limit)
+	right := totalizer(s, lits[mid:], weights[mid:], limit)
+
+	// Find every sum the outputs of this node can take
+	outputs := make(map[uint64]cnf.Lit)
+	output := func(value uint64) cnf.Lit {
+		if value > limit {
+			value = limit
+		}
+
+		lit, ok := outputs[value]
+		if !ok {
+			lit = newLit(s)
+			outputs[value] = lit
+		}
+
+		return lit
+	}
+
+	for _, a := range left {
+		addClause(s, a.lit.Neg(), output(a.value))
+	}
+	for _, b := range right {
+		addClause(s, b.lit.Neg(), output(b.value))
+	}
+	for _, a := range left {
+		for _, b := range right {
+			// Both values are at most limit so the sum is capped before
+			// it can overflow.
+			sum := limit
+			if a.value < limit-b.value {
+				sum = a.value + b.value
+			}
+
+			addClause(s, a.lit.Neg(), b.lit.Neg(), output(sum))
+		}
+	}
+
+	result := make([]totalizerOutput, 0, len(outputs))
+	for value, lit := range outputs {
+		result = append(result, totalizerOutput{value: value, lit: lit})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].value < result[j].value
+	})
+
+	return result
+}
+
+// exactlyOne adds clauses to the solver so that exactly one of lits is
+// true. At most one is encoded with the sequential counter encoding which
+// needs a linear number of clauses.
+func exactlyOne(s *sat.Solver, lits []cnf.Lit) {
+	addClause(s, lits...)
+
+	// prev is true if any of the literals so far is true
+	prev := lits[0]
+	for _, l := range lits[1:] {
+		addClause(s, prev.Neg(), l.Neg())
+
+		next := newLit(s)
+		addClause(s, prev.Neg(), next)
+		addClause(s, l.Neg(), next)
+		prev = next
+	}
+}
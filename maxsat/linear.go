@@ -0,0 +1,65 @@
+package maxsat
+
+import (
+	"context"
+
+	"github.com/mitchellh/go-sat"
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+// solveLinear solves the problem with linear SAT-UNSAT search.
+func (s *Solver) solveLinear(ctx context.Context, p *Problem) (*Solution, error) {
+	solver := s.newSolver(p)
+
+	// Every soft clause gets a relaxation literal that is true if the
+	// clause may be falsified. The cost of a model is at most the weight
+	// of the true relaxation literals.
+	var relax []cnf.Lit
+	var weights []uint64
+	for _, soft := range p.Soft {
+		if soft.Weight == 0 {
+			continue
+		}
+
+		r := newLit(solver)
+		addClause(solver, append(soft.Clause[:len(soft.Clause):len(soft.Clause)], r)...)
+		relax = append(relax, r)
+		weights = append(weights, soft.Weight)
+	}
+
+	var best *Solution
+	var outputs []totalizerOutput
+	for {
+		switch solver.SolveContext(ctx) {
+		case sat.ResultUnknown:
+			return best, interrupted(ctx)
+
+		case sat.ResultUnsat:
+			// No solution is better than the best, so it is optimal
+			if best == nil {
+				return nil, ErrUnsatisfiable
+			}
+
+			return best, nil
+		}
+
+		best = s.solution(p, solver, best)
+		if best.Cost == 0 {
+			return best, nil
+		}
+
+		// Encode the sum of the weights of the relaxation literals once
+		// we have the first upper bound. Every sum at or above that bound
+		// is the same output.
+		if outputs == nil {
+			outputs = totalizer(solver, relax, weights, best.Cost)
+		}
+
+		// The next solution must have a lower cost
+		for _, o := range outputs {
+			if o.value >= best.Cost {
+				addClause(solver, o.lit.Neg())
+			}
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Package maxsat solves weighted partial MaxSAT problems.
+//
+// A MaxSAT problem is made up of hard clauses, which must be satisfied,
+// and soft clauses, which each have a weight. The goal is to find an
+// assignment that satisfies every hard clause while minimizing the total
+// weight of the unsatisfied soft clauses (the cost).
+//
+// The problems are solved with the SAT solver in the root package. Two
+// algorithms are available:
+//
+//   - LinearSearch (linear SAT-UNSAT search) finds a solution and then
+//     repeatedly asks the SAT solver for a solution with a lower cost
+//     until there is none. The cost is constrained with a generalized
+//     totalizer encoding of the weighted sum. Every solution found is
+//     better than the last, so this is useful when a good solution is
+//     needed quickly.
+//
+//   - CoreGuided (stratified WPM1) repeatedly asks the SAT solver to
+//     satisfy every soft clause. If that is impossible the solver returns
+//     an unsatisfiable core, a set of soft clauses that can't all be
+//     satisfied, and the core is relaxed so that one of its clauses may be
+//     falsified. The lower bound on the cost increases with every core.
+//     This is often much faster when the optimal cost is low.
+package maxsat
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mitchellh/go-sat"
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+// ErrUnsatisfiable is returned when the hard clauses can't be satisfied.
+var ErrUnsatisfiable = errors.New("maxsat: hard clauses are unsatisfiable")
+
+// Problem is a weighted partial MaxSAT problem.
+type Problem struct {
+	Hard cnf.Formula // Hard clauses which must be satisfied
+	Soft []Soft      // Soft clauses which are satisfied if possible
+}
+
+// Soft is a soft clause and the cost of not satisfying it.
+type Soft struct {
+	Clause cnf.Clause
+	Weight uint64
+}
+
+// Cost returns the total weight of the soft clauses that are not satisfied
+// by the model. Variables that aren't in the model are false.
+func (p *Problem) Cost(model map[int]bool) uint64 {
+	var result uint64
+	for _, soft := range p.Soft {
+		if !satisfied(soft.Clause, model) {
+			result += soft.Weight
+		}
+	}
+
+	return result
+}
+
+// numVars returns the largest variable in the problem.
+func (p *Problem) numVars() int {
+	n := 0
+	max := func(c cnf.Clause) {
+		for _, l := range c {
+			if v := l.Var(); v > n {
+				n = v
+			}
+		}
+	}
+
+	for _, c := range p.Hard {
+		max(c)
+	}
+	for _, soft := range p.Soft {
+		max(soft.Clause)
+	}
+
+	return n
+}
+
+// Algorithm is the algorithm used to solve a problem. See the package
+// documentation for details on each.
+type Algorithm byte
+
+const (
+	LinearSearch Algorithm = iota
+	CoreGuided
+)
+
+// Solution is an assignment that satisfies the hard clauses of a problem.
+type Solution struct {
+	Model map[int]bool // Value of every variable in the problem
+	Cost  uint64       // Weight of the unsatisfied soft clauses
+}
+
+// Solver solves MaxSAT problems. The zero value solves problems using
+// LinearSearch and the default SAT solver options.
+type Solver struct {
+	// Algorithm is the algorithm used to solve problems.
+	Algorithm Algorithm
+
+	// Options are the options for the SAT solver used to solve problems.
+	// A RestartPolicy keeps state, so Options shouldn't be shared with
+	// other solvers.
+	Options sat.Options
+
+	// Progress, if non-nil, is called with every solution that is better
+	// than every solution found before it. The last call is with the
+	// optimal solution.
+	Progress func(*Solution)
+}
+
+// Solve finds an optimal solution to the problem using the default Solver.
+func Solve(ctx context.Context, p *Problem) (*Solution, error) {
+	var s Solver
+	return s.Solve(ctx, p)
+}
+
+// Solve finds an optimal solution to the problem.
+//
+// If ctx is cancelled then the best solution found so far is returned
+// along with ctx.Err(). The solution is nil if none was found. If the hard
+// clauses can't be satisfied then ErrUnsatisfiable is returned.
+func (s *Solver) Solve(ctx context.Context, p *Problem) (*Solution, error) {
+	switch s.Algorithm {
+	case CoreGuided:
+		return s.solveCore(ctx, p)
+
+	default:
+		return s.solveLinear(ctx, p)
+	}
+}
+
+// newSolver creates a SAT solver with the hard clauses of the problem.
+// Every variable of the problem is allocated so that new variables can be
+// allocated with NewVar.
+func (s *Solver) newSolver(p *Problem) *sat.Solver {
+	result := sat.New()
+	result.Options = s.Options
+	for _, c := range p.Hard {
+		addClause(result, c...)
+	}
+	for result.NumVars() < p.numVars() {
+		result.NewVar()
+	}
+
+	return result
+}
+
+// solution creates the solution from the current model of the SAT solver
+// and reports it if it is better than best. This returns the better of
+// the new solution and best.
+func (s *Solver) solution(p *Problem, solver *sat.Solver, best *Solution) *Solution {
+	n := p.numVars()
+	model := make(map[int]bool, n)
+	for v, value := range solver.Assignments() {
+		if v <= n {
+			model[v] = value
+		}
+	}
+
+	result := &Solution{Model: model, Cost: p.Cost(model)}
+	if best != nil && best.Cost <= result.Cost {
+		return best
+	}
+
+	if s.Progress != nil {
+		s.Progress(result)
+	}
+
+	return result
+}
+
+// interrupted returns the error for a solve that returned sat.ResultUnknown.
+func interrupted(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return sat.ErrBudget
+}
+
+// addClause adds a copy of the clause made of lits to the solver. The
+// solver modifies the clauses that are added so we never give it ours.
+func addClause(s *sat.Solver, lits ...cnf.Lit) {
+	c := make(cnf.Clause, len(lits))
+	copy(c, lits)
+	s.AddClause(c)
+}
+
+// newLit allocates a new variable and returns its positive literal.
+func newLit(s *sat.Solver) cnf.Lit {
+	return cnf.NewLit(s.NewVar(), false)
+}
+
+// satisfied returns true if the clause is satisfied by the model.
+func satisfied(c cnf.Clause, model map[int]bool) bool {
+	for _, l := range c {
+		if model[l.Var()] != l.Sign() {
+			return true
+		}
+	}
+
+	return false
+}
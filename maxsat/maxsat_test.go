@@ -0,0 +1,289 @@
+package maxsat
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/go-sat"
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+var algorithms = []Algorithm{LinearSearch, CoreGuided}
+
+func TestSolve(t *testing.T) {
+	cases := []struct {
+		Name string
+		Hard [][]int
+		Soft [][]int
+		Cost uint64
+	}{
+		{
+			"empty",
+			nil,
+			nil,
+			0,
+		},
+
+		{
+			"hard only",
+			[][]int{
+				[]int{1, 2},
+			},
+			nil,
+			0,
+		},
+
+		{
+			"conflicting softs",
+			nil,
+			[][]int{
+				[]int{3, 1},
+				[]int{2, -1},
+			},
+			2,
+		},
+
+		{
+			"hard forces cost",
+			[][]int{
+				[]int{-1, -2},
+			},
+			[][]int{
+				[]int{5, 1},
+				[]int{3, 2},
+				[]int{1, 3},
+			},
+			3,
+		},
+
+		{
+			"empty soft",
+			nil,
+			[][]int{
+				[]int{4},
+				[]int{1, 1},
+			},
+			4,
+		},
+
+		{
+			"zero weight",
+			nil,
+			[][]int{
+				[]int{0, 1},
+				[]int{0, -1},
+			},
+			0,
+		},
+
+		{
+			"at most one",
+			[][]int{
+				[]int{-1, -2},
+				[]int{-1, -3},
+				[]int{-2, -3},
+			},
+			[][]int{
+				[]int{2, 1},
+				[]int{2, 2},
+				[]int{2, 3},
+			},
+			4,
+		},
+
+		{
+			"implications",
+			[][]int{
+				[]int{-1, 2},
+				[]int{-2, 3},
+			},
+			[][]int{
+				[]int{4, 1},
+				[]int{3, -3},
+				[]int{2, -2},
+			},
+			4,
+		},
+
+		{
+			"trade off",
+			[][]int{
+				[]int{1, 2, 3},
+			},
+			[][]int{
+				[]int{5, -1},
+				[]int{4, -2},
+				[]int{3, -3},
+				[]int{1, 1, 2},
+			},
+			4,
+		},
+	}
+
+	for i, tc := range cases {
+		for _, alg := range algorithms {
+			t.Run(fmt.Sprintf("%d-%s-%d", i, tc.Name, alg), func(t *testing.T) {
+				p := problem(tc.Hard, tc.Soft)
+				s := &Solver{Algorithm: alg}
+				solution, err := s.Solve(context.Background(), p)
+				if err != nil {
+					t.Fatalf("err: %s", err)
+				}
+				if solution.Cost != tc.Cost {
+					t.Fatalf("bad: %d", solution.Cost)
+				}
+				if !satisfiesHard(p, solution.Model) {
+					t.Fatalf("bad model: %#v", solution.Model)
+				}
+			})
+		}
+	}
+}
+
+func TestSolve_unsatisfiable(t *testing.T) {
+	for _, alg := range algorithms {
+		p := problem([][]int{
+			[]int{1},
+			[]int{-1},
+		}, [][]int{
+			[]int{1, 2},
+		})
+
+		s := &Solver{Algorithm: alg}
+		solution, err := s.Solve(context.Background(), p)
+		if err != ErrUnsatisfiable {
+			t.Fatalf("err: %v", err)
+		}
+		if solution != nil {
+			t.Fatalf("bad: %#v", solution)
+		}
+	}
+}
+
+func TestSolve_cancel(t *testing.T) {
+	for _, alg := range algorithms {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		s := &Solver{Algorithm: alg}
+		_, err := s.Solve(ctx, problem(nil, [][]int{
+			[]int{1, 1},
+		}))
+		if err != context.Canceled {
+			t.Fatalf("err: %v", err)
+		}
+	}
+}
+
+func TestSolve_options(t *testing.T) {
+	for _, alg := range algorithms {
+		s := &Solver{Algorithm: alg}
+		s.Options.Decision = sat.DecisionVSIDS
+		s.Options.ClauseMinimization = true
+		solution, err := s.Solve(context.Background(), problem(nil, [][]int{
+			[]int{1, 1, 2},
+			[]int{2, -1},
+			[]int{3, -2},
+		}))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if solution.Cost != 1 {
+			t.Fatalf("bad: %d", solution.Cost)
+		}
+	}
+}
+
+func TestTotalizer_overflow(t *testing.T) {
+	// The sum of the weights overflows a uint64
+	const max = math.MaxUint64
+	s := sat.New()
+	lits := []cnf.Lit{newLit(s), newLit(s)}
+	outputs := totalizer(s, lits, []uint64{max - 10, max - 20}, max-5)
+
+	var values []uint64
+	for _, o := range outputs {
+		values = append(values, o.value)
+	}
+	expected := []uint64{max - 20, max - 10, max - 5}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("bad: %v", values)
+	}
+
+	// Both inputs reach the limit
+	addClause(s, lits[0])
+	addClause(s, lits[1])
+	addClause(s, outputs[2].lit.Neg())
+	if s.Solve() {
+		t.Fatal("should be unsatisfiable")
+	}
+}
+
+func TestSolve_progress(t *testing.T) {
+	p := problem([][]int{
+		[]int{1, 2},
+		[]int{-1, 3},
+		[]int{-2, 4},
+	}, [][]int{
+		[]int{3, -3},
+		[]int{3, -4},
+		[]int{2, -3, -4},
+		[]int{1, 1},
+	})
+
+	for _, alg := range algorithms {
+		var costs []uint64
+		s := &Solver{
+			Algorithm: alg,
+			Progress: func(s *Solution) {
+				costs = append(costs, s.Cost)
+			},
+		}
+
+		solution, err := s.Solve(context.Background(), p)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if solution.Cost != 3 {
+			t.Fatalf("bad: %d", solution.Cost)
+		}
+
+		// Progress is called with strictly better solutions, ending with
+		// the optimal solution.
+		for i := 1; i < len(costs); i++ {
+			if costs[i] >= costs[i-1] {
+				t.Fatalf("bad: %v", costs)
+			}
+		}
+		if len(costs) == 0 || costs[len(costs)-1] != solution.Cost {
+			t.Fatalf("bad: %v", costs)
+		}
+	}
+}
+
+// problem creates a problem from the hard clauses and the soft clauses,
+// where the first element of each soft clause is its weight.
+func problem(hard, soft [][]int) *Problem {
+	result := &Problem{Hard: cnf.NewFormulaFromInts(hard)}
+	for _, c := range soft {
+		result.Soft = append(result.Soft, Soft{
+			Clause: cnf.NewFormulaFromInts([][]int{c[1:]})[0],
+			Weight: uint64(c[0]),
+		})
+	}
+
+	return result
+}
+
+func satisfiesHard(p *Problem, model map[int]bool) bool {
+	for _, c := range p.Hard {
+		if !satisfied(c, model) {
+			return false
+		}
+	}
+
+	return true
+}
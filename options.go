@@ -0,0 +1,80 @@
+package sat
+
+import (
+	"math/rand"
+)
+
+// Options are the options that configure how a Solver searches for a
+// solution. The zero value is the default configuration.
+//
+// Options is embedded in Solver so every option can be set directly on a
+// solver. Setting all of the options at once makes it easy to try many
+// configurations on the same problem:
+//
+//	s := sat.New()
+//	s.Options = sat.Options{
+//		Decision: sat.DecisionVSIDS,
+//		Restart:  &sat.LubyRestart{},
+//		Deletion: sat.DeletionLBD,
+//	}
+//
+// The options should be set before solving. Restart policies keep state so
+// a policy must not be shared between solvers.
+type Options struct {
+	// Decision is the heuristic used to choose decision literals. This
+	// defaults to DecisionNaive. See DecisionHeuristic for more details.
+	Decision DecisionHeuristic
+
+	// Restart is the policy used to decide when to restart the search.
+	// If this is nil, the solver never restarts.
+	Restart RestartPolicy
+
+	// Deletion is the policy used to remove learned clauses. This defaults
+	// to DeletionNone. See DeletionPolicy for more details.
+	Deletion DeletionPolicy
+
+	// Polarity is the default value assigned to decision variables. This
+	// defaults to PolarityPositive. Use SetPolarity to set the value for
+	// individual variables.
+	Polarity Polarity
+
+	// PhaseSaving, if true, will assign decision variables the value they
+	// last had before they were unassigned by a backjump. This only falls
+	// back to Polarity if the variable was never assigned.
+	PhaseSaving bool
+
+	// ClauseMinimization, if true, removes redundant literals from learned
+	// clauses. A literal is redundant if it is implied by the other
+	// literals of the clause through the reasons of their assignments
+	// (recursive minimization, as in Minisat). Shorter clauses are cheaper
+	// to propagate and often prune more of the search.
+	ClauseMinimization bool
+
+	// Seed is the seed for every random choice made by the solver, such
+	// as PolarityRandom and RandomDecisionFreq. Zero uses a fixed default
+	// seed, so the solver is deterministic unless a different seed is set.
+	Seed int64
+
+	// RandomDecisionFreq is the probability, between 0 and 1, that a
+	// decision picks a random unassigned variable rather than the variable
+	// chosen by the Decision heuristic. A small amount of randomness can
+	// help the solver escape from unproductive parts of the search. This
+	// defaults to 0, which never makes random decisions.
+	RandomDecisionFreq float64
+}
+
+// random returns the source of randomness for the solver, seeded with
+// Seed. If Seed changes then the source is seeded again.
+func (s *Solver) random() *rand.Rand {
+	seed := s.Seed
+	if seed == 0 {
+		seed = randomSeed
+	}
+
+	if s.rand == nil || s.randSeed != seed {
+		s.rand = rand.New(rand.NewSource(seed))
+		s.randSeed = seed
+	}
+
+	return s.rand
+}
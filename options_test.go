@@ -0,0 +1,58 @@
+package sat
+
+import (
+	"testing"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+func TestSolverOptions(t *testing.T) {
+	s := New()
+	s.Options = Options{
+		Decision: DecisionVSIDS,
+		Polarity: PolarityNegative,
+	}
+
+	// The options are available directly on the solver
+	if s.Decision != DecisionVSIDS || s.Polarity != PolarityNegative {
+		t.Fatalf("bad: %#v", s.Options)
+	}
+}
+
+func TestSolverRandom_seed(t *testing.T) {
+	s := New()
+	first := s.random().Int63()
+
+	// Changing the seed starts a new sequence
+	s.Seed = 42
+	if v := s.random().Int63(); v == first {
+		t.Fatalf("bad: %d", v)
+	}
+
+	// Setting the default seed again restarts the default sequence
+	s.Seed = randomSeed
+	if v := s.random().Int63(); v != first {
+		t.Fatalf("bad: %d", v)
+	}
+}
+
+func TestSolverSelectLiteral_random(t *testing.T) {
+	s := New()
+	s.Decision = DecisionVSIDS
+	s.RandomDecisionFreq = 1
+	s.AddFormula(cnf.NewFormulaFromInts([][]int{
+		[]int{1, 2, 3, 4, 5, 6, 7, 8},
+	}))
+
+	// With the default heuristic every decision would be var 1, so
+	// random decisions should choose other vars.
+	other := false
+	for i := 0; i < 20; i++ {
+		if l := s.selectLiteral(); l.Var() != 1 {
+			other = true
+		}
+	}
+	if !other {
+		t.Fatal("no random decisions")
+	}
+}
@@ -0,0 +1,494 @@
+package proof
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+// VerifyDRAT verifies that the DRAT proof read from r refutes the formula
+// f, returning nil if the proof is valid. The proof may be in either the
+// text or the binary DRAT format; the format is detected automatically.
+//
+// Deletions of clauses that aren't in the formula are ignored.
+func VerifyDRAT(f cnf.Formula, r io.Reader) error {
+	c := newDRATChecker()
+	for _, clause := range f {
+		c.add(clause)
+	}
+
+	br := bufio.NewReader(r)
+	read := readDRATText
+	if isBinaryDRAT(br) {
+		read = readDRATBinary
+	}
+
+	var lits []cnf.Lit
+	for step := 1; ; step++ {
+		var del bool
+		var err error
+		del, lits, err = read(br, lits[:0])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("step %d: error reading proof: %s", step, err)
+		}
+
+		if del {
+			c.delete(lits)
+			continue
+		}
+
+		if !c.rup(lits) && !c.rat(lits) {
+			return fmt.Errorf(
+				"step %d: clause %s is neither RUP nor RAT", step, cnf.Clause(lits))
+		}
+
+		// Once we have the empty clause we're done.
+		if len(lits) == 0 {
+			return nil
+		}
+
+		c.add(lits)
+	}
+
+	// Unit propagation alone may have refuted the formula
+	if c.conflict {
+		return nil
+	}
+
+	return ErrIncomplete
+}
+
+// isBinaryDRAT peeks at the start of the proof to determine if it is in
+// the binary format. Text proofs only contain a small set of characters
+// while binary proofs start with 'a' or 'd' followed by arbitrary bytes.
+func isBinaryDRAT(r *bufio.Reader) bool {
+	start, _ := r.Peek(10)
+	if len(start) == 0 {
+		return false
+	}
+	if start[0] == 'a' {
+		return true
+	}
+
+	// Comments can contain anything, but a binary proof never starts
+	// with a comment.
+	if start[0] == 'c' {
+		return false
+	}
+
+	for _, b := range start {
+		switch {
+		case b >= '0' && b <= '9':
+		case b == '-', b == 'd', b == ' ', b == '\t', b == '\r', b == '\n':
+		default:
+			return true
+		}
+	}
+
+	return false
+}
+
+// readDRATText reads a single step of a text DRAT proof.
+func readDRATText(r *bufio.Reader, buf []cnf.Lit) (bool, []cnf.Lit, error) {
+	t := &textReader{r: r}
+	b, err := t.peek()
+	if err != nil {
+		return false, buf, err
+	}
+
+	del := b == 'd'
+	if del {
+		r.ReadByte()
+	}
+
+	buf, err = t.readLits(buf)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	return del, buf, err
+}
+
+// readDRATBinary reads a single step of a binary DRAT proof.
+func readDRATBinary(r *bufio.Reader, buf []cnf.Lit) (bool, []cnf.Lit, error) {
+	kind, err := r.ReadByte()
+	if err != nil {
+		return false, buf, err
+	}
+	if kind != 'a' && kind != 'd' {
+		return false, buf, fmt.Errorf("invalid binary step %q", kind)
+	}
+
+	for {
+		// Every literal is a variable length integer that is the
+		// variable times two plus the sign.
+		var u uint
+		for shift := uint(0); ; shift += 7 {
+			b, err := r.ReadByte()
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				return false, buf, err
+			}
+
+			u |= uint(b&0x7f) << shift
+			if b&0x80 == 0 {
+				break
+			}
+		}
+
+		if u == 0 {
+			return kind == 'd', buf, nil
+		}
+
+		buf = append(buf, cnf.Lit(u))
+	}
+}
+
+// dratChecker checks DRAT proof steps. It keeps the assignment that results
+// from unit propagation on the current formula (the base assignment) and
+// uses watched literals for unit propagation.
+type dratChecker struct {
+	assignment
+
+	clauses []dratClause
+	watches [][]int          // clauses watching each literal
+	index   map[uint64][]int // clause hash to clauses for deletion
+	marks   []bool           // scratch marks indexed by literal
+	qhead   int              // next literal in the trail to propagate
+	base    int              // length of the trail for the base assignment
+
+	// conflict is true if unit propagation on the formula alone results
+	// in a conflict. At that point every clause is RUP.
+	conflict bool
+
+	resolvent []cnf.Lit // scratch space for RAT checks
+}
+
+type dratClause struct {
+	lits    []cnf.Lit
+	deleted bool
+}
+
+func newDRATChecker() *dratChecker {
+	return &dratChecker{index: make(map[uint64][]int)}
+}
+
+// grow makes sure the literal l can be used in the checker.
+func (c *dratChecker) grow(l cnf.Lit) {
+	c.assignment.grow(l)
+	if need := int(l|1) + 1; need > len(c.watches) {
+		c.watches = append(c.watches, make([][]int, need-len(c.watches))...)
+		c.marks = append(c.marks, make([]bool, need-len(c.marks))...)
+	}
+}
+
+// add adds a clause to the formula.
+func (c *dratChecker) add(lits []cnf.Lit) {
+	// Copy the clause, removing any duplicate literals
+	clause := make([]cnf.Lit, 0, len(lits))
+	for _, l := range lits {
+		c.grow(l)
+		if !c.marks[l] {
+			c.marks[l] = true
+			clause = append(clause, l)
+		}
+	}
+	for _, l := range clause {
+		c.marks[l] = false
+	}
+
+	id := len(c.clauses)
+	c.clauses = append(c.clauses, dratClause{lits: clause})
+	h := hashLits(clause)
+	c.index[h] = append(c.index[h], id)
+
+	c.attach(id)
+}
+
+// delete removes a clause from the formula.
+func (c *dratChecker) delete(lits []cnf.Lit) {
+	// Mark the literals, removing duplicates
+	del := c.resolvent[:0]
+	for _, l := range lits {
+		c.grow(l)
+		if !c.marks[l] {
+			c.marks[l] = true
+			del = append(del, l)
+		}
+	}
+	c.resolvent = del
+	defer func() {
+		for _, l := range del {
+			c.marks[l] = false
+		}
+	}()
+
+	h := hashLits(del)
+	ids := c.index[h]
+	for i, id := range ids {
+		clause := &c.clauses[id]
+		if !c.matches(clause.lits, len(del)) {
+			continue
+		}
+
+		clause.deleted = true
+		c.index[h] = append(ids[:i], ids[i+1:]...)
+
+		// If the clause may be responsible for part of the base assignment
+		// then we have to recompute the base assignment without it.
+		if c.reason(clause.lits) {
+			c.rebuild()
+		}
+
+		return
+	}
+}
+
+// matches returns true if lits are exactly the marked literals, given
+// that count distinct literals are marked.
+func (c *dratChecker) matches(lits []cnf.Lit, count int) bool {
+	if len(lits) != count {
+		return false
+	}
+
+	for _, l := range lits {
+		if !c.marks[l] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// reason returns true if the clause could have implied a literal in the
+// base assignment: one literal is true and the rest are false.
+func (c *dratChecker) reason(lits []cnf.Lit) bool {
+	if c.conflict {
+		return true
+	}
+
+	trueCount := 0
+	for _, l := range lits {
+		switch c.value(l) {
+		case 1:
+			trueCount++
+
+		case 0:
+			return false
+		}
+	}
+
+	return trueCount == 1
+}
+
+// rebuild recomputes the base assignment and watches from scratch.
+func (c *dratChecker) rebuild() {
+	c.undo(0)
+	c.qhead = 0
+	c.base = 0
+	c.conflict = false
+	for i := range c.watches {
+		c.watches[i] = c.watches[i][:0]
+	}
+
+	for id := range c.clauses {
+		if !c.clauses[id].deleted {
+			c.attach(id)
+		}
+	}
+}
+
+// attach watches the clause with the given id and extends the base
+// assignment with unit propagation.
+func (c *dratChecker) attach(id int) {
+	if c.conflict {
+		return
+	}
+
+	lits := c.clauses[id].lits
+	switch len(lits) {
+	case 0:
+		c.conflict = true
+		return
+
+	case 1:
+		// Unit clauses aren't watched, they're only part of the base
+		// assignment.
+		switch c.value(lits[0]) {
+		case -1:
+			c.conflict = true
+			return
+
+		case 0:
+			c.assign(lits[0])
+		}
+
+	default:
+		// Move the literals that aren't false to the front to watch them
+		for w := 0; w < 2; w++ {
+			for k := w; k < len(lits); k++ {
+				if c.value(lits[k]) != -1 {
+					lits[w], lits[k] = lits[k], lits[w]
+					break
+				}
+			}
+		}
+
+		c.watches[lits[0]] = append(c.watches[lits[0]], id)
+		c.watches[lits[1]] = append(c.watches[lits[1]], id)
+
+		if c.value(lits[0]) == -1 {
+			c.conflict = true
+			return
+		}
+		if c.value(lits[1]) == -1 && c.value(lits[0]) == 0 {
+			c.assign(lits[0])
+		}
+	}
+
+	if c.propagate() {
+		c.conflict = true
+		return
+	}
+
+	c.base = len(c.trail)
+}
+
+// propagate performs unit propagation, returning true on a conflict.
+func (c *dratChecker) propagate() bool {
+	for c.qhead < len(c.trail) {
+		falseLit := c.trail[c.qhead].Neg()
+		c.qhead++
+
+		ws := c.watches[falseLit]
+		i, j := 0, 0
+	WATCH_LOOP:
+		for i < len(ws) {
+			id := ws[i]
+			i++
+
+			clause := &c.clauses[id]
+			if clause.deleted {
+				continue
+			}
+
+			// Keep the false literal in position 1
+			lits := clause.lits
+			if lits[0] == falseLit {
+				lits[0], lits[1] = lits[1], lits[0]
+			}
+
+			if c.value(lits[0]) == 1 {
+				ws[j] = id
+				j++
+				continue
+			}
+
+			// Look for a new literal to watch
+			for k := 2; k < len(lits); k++ {
+				if c.value(lits[k]) != -1 {
+					lits[1], lits[k] = lits[k], lits[1]
+					c.watches[lits[1]] = append(c.watches[lits[1]], id)
+					continue WATCH_LOOP
+				}
+			}
+
+			// Unit or conflict
+			ws[j] = id
+			j++
+			if c.value(lits[0]) == -1 {
+				j += copy(ws[j:], ws[i:])
+				c.watches[falseLit] = ws[:j]
+				return true
+			}
+
+			c.assign(lits[0])
+		}
+
+		c.watches[falseLit] = ws[:j]
+	}
+
+	return false
+}
+
+// rup returns true if the clause is a reverse unit propagation (RUP)
+// clause: assigning the negation of every literal and propagating results
+// in a conflict.
+func (c *dratChecker) rup(lits []cnf.Lit) bool {
+	if c.conflict {
+		return true
+	}
+
+	result := false
+	for _, l := range lits {
+		c.grow(l)
+		switch c.value(l) {
+		case 1:
+			result = true
+
+		case 0:
+			c.assign(l.Neg())
+		}
+
+		if result {
+			break
+		}
+	}
+
+	if !result {
+		result = c.propagate()
+	}
+
+	c.undo(c.base)
+	c.qhead = c.base
+	return result
+}
+
+// rat returns true if the clause is a resolution asymmetric tautology (RAT)
+// on its first literal: every resolvent with a clause containing the
+// negation of that literal is RUP.
+func (c *dratChecker) rat(lits []cnf.Lit) bool {
+	if len(lits) == 0 {
+		return false
+	}
+
+	pivot := lits[0]
+	for id := range c.clauses {
+		d := &c.clauses[id]
+		if d.deleted || !contains(d.lits, pivot.Neg()) {
+			continue
+		}
+
+		resolvent := append(c.resolvent[:0], lits...)
+		for _, l := range d.lits {
+			if l != pivot.Neg() {
+				resolvent = append(resolvent, l)
+			}
+		}
+		c.resolvent = resolvent
+
+		if !c.rup(resolvent) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hashLits returns a hash of the literals that doesn't depend on their order.
+func hashLits(lits []cnf.Lit) uint64 {
+	var result uint64
+	for _, l := range lits {
+		h := uint64(l) * 0x9e3779b97f4a7c15
+		result += h ^ (h >> 29)
+	}
+
+	return result
+}
@@ -0,0 +1,166 @@
+package proof
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+func TestVerifyDRAT(t *testing.T) {
+	// All four clauses over two variables
+	full := [][]int{
+		[]int{1, 2},
+		[]int{1, -2},
+		[]int{-1, 2},
+		[]int{-1, -2},
+	}
+
+	cases := []struct {
+		Name    string
+		Formula [][]int
+		Proof   string
+		Err     bool
+	}{
+		{
+			"rup",
+			full,
+			"1 0\n0\n",
+			false,
+		},
+
+		{
+			"comments and deletions",
+			full,
+			"c a comment\n1 0\nd 1 2 0\nd 1 -2 0\n0\n",
+			false,
+		},
+
+		{
+			"deleting a needed clause",
+			full,
+			"1 0\nd -1 2 0\n0\n",
+			true,
+		},
+
+		{
+			"deleting a unit clause",
+			[][]int{
+				[]int{1},
+				[]int{-1, 2},
+				[]int{-2, 3},
+			},
+			"d 1 0\n-3 0\n",
+			true,
+		},
+
+		{
+			"not implied",
+			full,
+			"3 0\n",
+			true,
+		},
+
+		{
+			"rat",
+			[][]int{
+				[]int{-2, 1},
+				[]int{-4, 1},
+				[]int{5, 6},
+				[]int{5, -6},
+				[]int{-5, 6},
+				[]int{-5, -6},
+			},
+			// 4 -2 is not RUP but resolving with -4 1 gives -2 1.
+			"4 -2 0\n5 0\n0\n",
+			false,
+		},
+
+		{
+			"rat on new variable",
+			full,
+			"3 0\n1 0\n0\n",
+			false,
+		},
+
+		{
+			"not rat",
+			[][]int{
+				[]int{1, 2},
+				[]int{-4, 3},
+			},
+			"4 -1 0\n",
+			true,
+		},
+
+		{
+			"trivially unsat formula",
+			[][]int{
+				[]int{1},
+				[]int{-1},
+			},
+			"",
+			false,
+		},
+
+		{
+			"incomplete",
+			full,
+			"1 2 0\n",
+			true,
+		},
+
+		{
+			"unterminated clause",
+			full,
+			"1 0\n0",
+			false,
+		},
+
+		{
+			"unterminated lemma",
+			full,
+			"1",
+			true,
+		},
+
+		{
+			"binary",
+			full,
+			"a\x02\x00a\x00",
+			false,
+		},
+
+		{
+			"binary deletion",
+			full,
+			"a\x02\x00d\x02\x04\x00a\x00",
+			false,
+		},
+
+		{
+			"binary not implied",
+			full,
+			"a\x06\x00",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d-%s", i, tc.Name), func(t *testing.T) {
+			f := cnf.NewFormulaFromInts(tc.Formula)
+			err := VerifyDRAT(f, strings.NewReader(tc.Proof))
+			if (err != nil) != tc.Err {
+				t.Fatalf("bad: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyDRAT_incomplete(t *testing.T) {
+	f := cnf.NewFormulaFromInts([][]int{[]int{1, 2}})
+	if err := VerifyDRAT(f, strings.NewReader("1 2 0\n")); err != ErrIncomplete {
+		t.Fatalf("bad: %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package proof
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+// VerifyLRAT verifies that the LRAT proof read from r refutes the formula
+// f, returning nil if the proof is valid. Only the text LRAT format is
+// supported.
+//
+// The clauses of the formula are identified by their position in f,
+// starting at 1. Every step of the proof is one of:
+//
+//	<id> <literals> 0 <hints> 0     (add a clause)
+//	<id> d <ids> 0                  (delete clauses)
+//
+// The hints of an added clause are the clauses that become unit, in order,
+// when the negation of the clause is assigned. A negative hint starts the
+// hints for the RAT check against that clause.
+func VerifyLRAT(f cnf.Formula, r io.Reader) error {
+	c := &lratChecker{clauses: make(map[int][]cnf.Lit, len(f))}
+	for i, clause := range f {
+		c.clauses[i+1] = append([]cnf.Lit(nil), clause...)
+	}
+
+	t := &textReader{r: bufio.NewReader(r)}
+	var lits []cnf.Lit
+	var hints []int
+	for step := 1; ; step++ {
+		if _, err := t.peek(); err == io.EOF {
+			break
+		}
+
+		id, err := t.readInt()
+		if err != nil {
+			return fmt.Errorf("step %d: error reading proof: %s", step, err)
+		}
+
+		// Deletion
+		if b, _ := t.peek(); b == 'd' {
+			t.r.ReadByte()
+			hints, err = readIDs(t, hints[:0])
+			if err != nil {
+				return fmt.Errorf("step %d: error reading proof: %s", step, err)
+			}
+
+			for _, id := range hints {
+				delete(c.clauses, id)
+			}
+
+			continue
+		}
+
+		lits, err = t.readLits(lits[:0])
+		if err == nil {
+			hints, err = readIDs(t, hints[:0])
+		}
+		if err != nil {
+			return fmt.Errorf("step %d: error reading proof: %s", step, err)
+		}
+
+		if _, ok := c.clauses[id]; ok {
+			return fmt.Errorf("step %d: clause id %d already exists", step, id)
+		}
+
+		if err := c.check(lits, hints); err != nil {
+			return fmt.Errorf("step %d: clause %d %s: %s", step, id, cnf.Clause(lits), err)
+		}
+
+		// Once we have the empty clause we're done.
+		if len(lits) == 0 {
+			return nil
+		}
+
+		c.clauses[id] = append([]cnf.Lit(nil), lits...)
+	}
+
+	return ErrIncomplete
+}
+
+// readIDs reads clause ids until a terminating zero and appends them to buf.
+func readIDs(t *textReader, buf []int) ([]int, error) {
+	for {
+		v, err := t.readInt()
+		if err != nil {
+			return buf, err
+		}
+		if v == 0 {
+			return buf, nil
+		}
+
+		buf = append(buf, v)
+	}
+}
+
+// lratChecker checks LRAT proof steps. Hints make unit propagation
+// explicit so the checker only needs the clauses and an assignment.
+type lratChecker struct {
+	assignment
+
+	clauses map[int][]cnf.Lit
+}
+
+// check verifies that the clause lits is implied by the current formula
+// using the given hints.
+func (c *lratChecker) check(lits []cnf.Lit, hints []int) error {
+	defer c.undo(0)
+
+	// Assign the negation of the clause. If the clause is a tautology
+	// then it is trivially implied.
+	for _, l := range lits {
+		switch c.value(l) {
+		case 1:
+			return nil
+
+		case 0:
+			c.assign(l.Neg())
+		}
+	}
+
+	// RUP: follow the positive hints until a conflict
+	i := 0
+	for ; i < len(hints) && hints[i] > 0; i++ {
+		conflict, err := c.unit(hints[i])
+		if err != nil {
+			return err
+		}
+		if conflict {
+			return nil
+		}
+	}
+
+	// RAT: every clause containing the negated pivot must be handled by
+	// a group of hints starting with its negated id.
+	if len(lits) == 0 {
+		return fmt.Errorf("hints did not result in a conflict")
+	}
+
+	groups := make(map[int][]int)
+	for i < len(hints) {
+		id := -hints[i]
+		i++
+
+		start := i
+		for i < len(hints) && hints[i] > 0 {
+			i++
+		}
+
+		groups[id] = hints[start:i]
+	}
+
+	pivot := lits[0]
+	mark := len(c.trail)
+	for id, d := range c.clauses {
+		if !contains(d, pivot.Neg()) {
+			continue
+		}
+
+		if err := c.checkRAT(id, d, pivot, groups); err != nil {
+			return err
+		}
+
+		c.undo(mark)
+	}
+
+	return nil
+}
+
+// checkRAT verifies the resolvent of the clause being checked (already
+// assigned false) and the clause d on the pivot.
+func (c *lratChecker) checkRAT(id int, d []cnf.Lit, pivot cnf.Lit, groups map[int][]int) error {
+	for _, l := range d {
+		if l == pivot.Neg() {
+			continue
+		}
+
+		switch c.value(l) {
+		case 1:
+			// The resolvent is satisfied so it is trivially implied
+			return nil
+
+		case 0:
+			c.assign(l.Neg())
+		}
+	}
+
+	hints, ok := groups[id]
+	if !ok {
+		return fmt.Errorf("no RAT hints for clause %d", id)
+	}
+
+	for _, h := range hints {
+		conflict, err := c.unit(h)
+		if err != nil {
+			return err
+		}
+		if conflict {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("RAT hints for clause %d did not result in a conflict", id)
+}
+
+// unit processes the hint clause id which must either be unit, in which
+// case the remaining literal is assigned, or false, which is a conflict.
+func (c *lratChecker) unit(id int) (bool, error) {
+	clause, ok := c.clauses[id]
+	if !ok {
+		return false, fmt.Errorf("hint %d is not an active clause", id)
+	}
+
+	unassigned := cnf.LitUndef
+	for _, l := range clause {
+		switch c.value(l) {
+		case 1:
+			return false, fmt.Errorf("hint %d is satisfied", id)
+
+		case 0:
+			if unassigned != cnf.LitUndef && unassigned != l {
+				return false, fmt.Errorf("hint %d is not unit", id)
+			}
+
+			unassigned = l
+		}
+	}
+
+	if unassigned == cnf.LitUndef {
+		return true, nil
+	}
+
+	c.assign(unassigned)
+	return false, nil
+}
@@ -0,0 +1,127 @@
+package proof
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+func TestVerifyLRAT(t *testing.T) {
+	// All four clauses over two variables
+	full := [][]int{
+		[]int{1, 2},
+		[]int{1, -2},
+		[]int{-1, 2},
+		[]int{-1, -2},
+	}
+
+	cases := []struct {
+		Name    string
+		Formula [][]int
+		Proof   string
+		Err     bool
+	}{
+		{
+			"rup",
+			full,
+			"5 1 0 1 2 0\n6 0 5 3 4 0\n",
+			false,
+		},
+
+		{
+			"comments and deletions",
+			full,
+			"c a comment\n5 1 0 1 2 0\n5 d 1 2 0\n6 0 5 3 4 0\n",
+			false,
+		},
+
+		{
+			"hint deleted",
+			full,
+			"5 1 0 1 2 0\n5 d 5 0\n6 0 5 3 4 0\n",
+			true,
+		},
+
+		{
+			"hint satisfied",
+			full,
+			"5 1 0 3 0\n",
+			true,
+		},
+
+		{
+			"hint not unit",
+			full,
+			"5 1 0 4 1 2 0\n",
+			true,
+		},
+
+		{
+			"no conflict",
+			full,
+			"5 1 0 1 0\n",
+			true,
+		},
+
+		{
+			"existing id",
+			full,
+			"4 1 0 1 2 0\n",
+			true,
+		},
+
+		{
+			"rat",
+			[][]int{
+				[]int{-2, 1},
+				[]int{-4, 1},
+				[]int{5, 6},
+				[]int{5, -6},
+				[]int{-5, 6},
+				[]int{-5, -6},
+			},
+			"7 4 -2 0 -2 1 0\n8 5 0 3 4 0\n9 0 8 5 6 0\n",
+			false,
+		},
+
+		{
+			"rat without hints",
+			[][]int{
+				[]int{-2, 1},
+				[]int{-4, 1},
+				[]int{5, 6},
+				[]int{5, -6},
+				[]int{-5, 6},
+				[]int{-5, -6},
+			},
+			"7 4 -2 0 0\n8 5 0 3 4 0\n9 0 8 5 6 0\n",
+			true,
+		},
+
+		{
+			"rat on new variable",
+			full,
+			"5 3 0 0\n6 1 0 1 2 0\n7 0 6 3 4 0\n",
+			false,
+		},
+
+		{
+			"incomplete",
+			full,
+			"5 1 0 1 2 0\n",
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d-%s", i, tc.Name), func(t *testing.T) {
+			f := cnf.NewFormulaFromInts(tc.Formula)
+			err := VerifyLRAT(f, strings.NewReader(tc.Proof))
+			if (err != nil) != tc.Err {
+				t.Fatalf("bad: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+// Package proof verifies proofs of unsatisfiability for formulas in CNF.
+//
+// A SAT solver that claims a formula is unsatisfiable can produce a proof
+// of that claim: a sequence of clauses that are each implied by the formula
+// and the prior clauses, ending in the empty clause. Verifying the proof
+// with an independent checker certifies the result without having to trust
+// the solver.
+//
+// Two proof formats are supported:
+//
+//   - DRAT, in both the text and binary form. Every added clause must be
+//     RUP (reverse unit propagation) or RAT (resolution asymmetric
+//     tautology) with respect to the current formula.
+//
+//   - LRAT (text form), which extends DRAT with clause identifiers and
+//     hints that make checking much cheaper.
+//
+// DRAT proofs are checked in forward order, verifying every clause in the
+// proof rather than only the clauses necessary for the final refutation.
+package proof
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+// ErrIncomplete is returned when the proof is valid so far but doesn't
+// derive the empty clause.
+var ErrIncomplete = errors.New("proof does not derive the empty clause")
+
+// assignment is a partial assignment of literals with a trail so that
+// assignments can be undone.
+type assignment struct {
+	vals  []int8    // value for each literal: 1 true, -1 false, 0 unassigned
+	trail []cnf.Lit // assigned literals in order
+}
+
+// value returns the value of the literal l.
+func (a *assignment) value(l cnf.Lit) int8 {
+	if int(l) >= len(a.vals) {
+		return 0
+	}
+
+	return a.vals[l]
+}
+
+// grow makes sure the literal l (and its negation) can be assigned.
+func (a *assignment) grow(l cnf.Lit) {
+	if need := int(l|1) + 1; need > len(a.vals) {
+		a.vals = append(a.vals, make([]int8, need-len(a.vals))...)
+	}
+}
+
+// assign sets the literal l to true.
+func (a *assignment) assign(l cnf.Lit) {
+	a.grow(l)
+	a.vals[l] = 1
+	a.vals[l.Neg()] = -1
+	a.trail = append(a.trail, l)
+}
+
+// undo unassigns every literal after the first n literals of the trail.
+func (a *assignment) undo(n int) {
+	for _, l := range a.trail[n:] {
+		a.vals[l] = 0
+		a.vals[l.Neg()] = 0
+	}
+
+	a.trail = a.trail[:n]
+}
+
+// textReader reads the integers of a proof in text form. Comment lines
+// starting with "c" are skipped.
+type textReader struct {
+	r *bufio.Reader
+}
+
+// peek skips whitespace and comments and returns the next byte without
+// consuming it. This returns io.EOF at the end of the input.
+func (t *textReader) peek() (byte, error) {
+	for {
+		b, err := t.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+
+		case 'c':
+			if _, err := t.r.ReadBytes('\n'); err != nil && err != io.EOF {
+				return 0, err
+			}
+
+			continue
+		}
+
+		return b, t.r.UnreadByte()
+	}
+}
+
+// readInt reads the next integer.
+func (t *textReader) readInt() (int, error) {
+	b, err := t.peek()
+	if err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	neg := b == '-'
+	if neg {
+		t.r.ReadByte()
+	}
+
+	result, digits := 0, 0
+	for {
+		b, err := t.r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if b < '0' || b > '9' {
+			t.r.UnreadByte()
+			break
+		}
+
+		result = result*10 + int(b-'0')
+		digits++
+	}
+
+	if digits == 0 {
+		b, _ := t.r.ReadByte()
+		return 0, fmt.Errorf("unexpected character %q", b)
+	}
+
+	if neg {
+		result = -result
+	}
+
+	return result, nil
+}
+
+// readLits reads literals until a terminating zero and appends them to buf.
+func (t *textReader) readLits(buf []cnf.Lit) ([]cnf.Lit, error) {
+	for {
+		v, err := t.readInt()
+		if err != nil {
+			return buf, err
+		}
+		if v == 0 {
+			return buf, nil
+		}
+
+		buf = append(buf, cnf.NewLitInt(v))
+	}
+}
+
+// contains returns true if lits contains the literal l.
+func contains(lits []cnf.Lit, l cnf.Lit) bool {
+	for _, current := range lits {
+		if current == l {
+			return true
+		}
+	}
+
+	return false
+}
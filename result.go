@@ -0,0 +1,31 @@
+package sat
+
+// Result is the result of solving a formula.
+type Result byte
+
+const (
+	// ResultUnknown means the solver didn't determine whether the formula
+	// is satisfiable. This happens if solving was interrupted.
+	ResultUnknown Result = iota
+
+	// ResultUnsat means the formula is unsatisfiable.
+	ResultUnsat
+
+	// ResultSat means the formula is satisfiable.
+	ResultSat
+)
+
+// String returns the result in the form used by the SAT competition output
+// format: SATISFIABLE, UNSATISFIABLE or UNKNOWN.
+func (r Result) String() string {
+	switch r {
+	case ResultSat:
+		return "SATISFIABLE"
+
+	case ResultUnsat:
+		return "UNSATISFIABLE"
+
+	default:
+		return "UNKNOWN"
+	}
+}
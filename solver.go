@@ -1,6 +1,10 @@
 package sat
 
 import (
+	"context"
+	"io"
+	"math/rand"
+
 	"github.com/mitchellh/go-sat/cnf"
 )
 
@@ -10,15 +14,25 @@ import (
 // manually allocated.
 //
 // Add clauses or a formula using the AddClause and AddFormula functions,
-// respectively. These must be called prior to calling Solve().
+// respectively. These may be called before Solve() as well as between
+// calls to Solve().
+//
+// Variables are numbered from 1 and allocated densely: NewVar allocates
+// the next variable and adding a clause allocates every variable up to
+// the largest variable in the clause.
 //
 // Solve() will attempt to solve the problem, returning false on
 // unsatisfiability and true on satisfiability. A sufficiently complex
-// SAT problem may take a very long time (this solver currently doesn't
-// allow time budgeting).
+// SAT problem may take a very long time. SolveContext() can be used to
+// interrupt solving with a context. SolveWithAssumptions() can be used to
+// solve the problem with a set of literals temporarily assumed to be true.
 //
 // Assignments() can be called after Solve() returns true to get the
 // assigned values for a solution.
+//
+// Solving is deterministic: the same clauses added in the same order with
+// the same Options (including Seed) always result in the same search and
+// the same solution.
 type Solver struct {
 	// Trace, if set to true, will output trace debugging information
 	// via the standard library `log` package. If true, Tracer must also
@@ -27,22 +41,74 @@ type Solver struct {
 	Trace  bool
 	Tracer Tracer
 
+	// Options configures the search. The options can be set directly on
+	// the solver, e.g. s.Decision, or all at once by assigning to
+	// s.Options. See Options for more details.
+	Options
+
+	// ConflictBudget, DecisionBudget and PropagationBudget limit the
+	// number of conflicts, decisions and propagations, respectively, that
+	// a single call to a Solve function may use. If any budget is
+	// exhausted then SolveContext returns ResultUnknown and solving can be
+	// resumed by calling it again. Unlike a context deadline, budgets are
+	// deterministic. A budget of zero means there is no limit.
+	ConflictBudget    int64
+	DecisionBudget    int64
+	PropagationBudget int64
+
+	// Proof, if non-nil, is where a DRAT proof is written. Every learned
+	// and deleted clause is written so that an unsatisfiable result can be
+	// verified by an independent proof checker such as drat-trim. The
+	// proof is written in the format specified by ProofFormat. These must
+	// be set before any clauses are added. Writing a proof requires that
+	// variables start at 1. See ProofError for write errors.
+	Proof       io.Writer
+	ProofFormat ProofFormat
+
 	//---------------------------------------------------------------
 	// Internal fields, do not set
 	//---------------------------------------------------------------
-	result satResult
+	result      Result
+	assumptions []cnf.Lit // assumptions for the current solve
+	failed      []cnf.Lit // failed assumptions of the last solve
+
+	// statistics, budgetStart is a copy of stats at the start of the
+	// current solve so we can determine the work done for budgeting.
+	stats       Stats
+	budgetStart Stats
 
 	// problem
-	clauses []cnf.Clause     // clauses to solve
-	vars    map[int]struct{} // list of available vars
+	arena   clauseArena // storage for every clause
+	clauses []cref      // clauses to solve
+	numVars int         // number of allocated vars, vars are 1..numVars
+	enumVar int         // activation var for Enumerate, see allocVars
+
+	// learned clause database
+	learnedClauses  []learnedClause // learned clauses (excluding units)
+	reduceConflicts int             // conflicts since the last reduction
+	reduceCount     int             // number of reductions so far
 
 	// two-literal watching
 	qhead   int
-	watches map[cnf.Lit][]*watcher
+	watches [][]watcher // watches for each literal, indexed by literal
 
 	// clause learning state
-	seen    map[int]int8
-	learned []cnf.Lit // current learned clause
+	seen      []int8    // marks for each var, indexed by var
+	learned   []cnf.Lit // current learned clause
+	toClear   []cnf.Lit // literals with seen marks set by minimization
+	minStack  []cnf.Lit // stack for minimization
+	lbdLevels []uint64  // level marks for computing LBD
+	lbdStamp  uint64    // current mark for lbdLevels
+
+	// proof output
+	proof *proofWriter
+
+	// decision heuristic
+	order        *varOrder
+	phases       []tribool  // saved phases (last value) for each var
+	userPolarity []tribool  // polarity set with SetPolarity for each var
+	rand         *rand.Rand // source of randomness, see random
+	randSeed     int64      // seed that rand was created with
 
 	//---------------------------------------------------------------
 	// trail
@@ -56,63 +122,169 @@ type Solver struct {
 	// trailIdx[level] = index to the start of that level in trail
 	trailIdx []int
 
-	// assigns keeps track of variable assignment values. unassigned variables
-	// are never present in assigns.
-	assigns map[int]tribool
+	// assigns keeps track of variable assignment values, indexed by
+	// variable. unassigned variables are triUndef.
+	assigns []tribool
 
-	// varinfo holds information about an assigned variable. unassigned
-	// variables may be present here but their resulting information is
-	// garbage.
-	varinfo map[int]varinfo
+	// varinfo holds information about an assigned variable, indexed by
+	// variable. the information for unassigned variables is garbage.
+	varinfo []varinfo
 }
 
 // New creates a new solver and allocates the basics for it.
 func New() *Solver {
-	return &Solver{
-		result: satResultUndef,
+	s := &Solver{
+		result: ResultUnknown,
+
+		// clause learning
+		learned: make([]cnf.Lit, 0, 10),
+
+		// decision heuristic
+		order: newVarOrder(),
+	}
 
-		// problem
-		vars: make(map[int]struct{}),
+	// Allocate the state for var 0 even though it is never used so that
+	// every slice can be indexed directly by the var.
+	s.growVars(0)
+	return s
+}
 
-		// trail
-		assigns: make(map[int]tribool),
-		varinfo: make(map[int]varinfo),
+// NewVar allocates a new variable and returns it. Variables are allocated
+// in order starting at 1.
+func (s *Solver) NewVar() int {
+	s.growVars(s.numVars + 1)
+	return s.numVars
+}
 
-		// two-literal watches
-		watches: make(map[cnf.Lit][]*watcher),
+// NumVars returns the number of allocated variables. The variables of the
+// solver are 1 through NumVars.
+func (s *Solver) NumVars() int {
+	return s.numVars
+}
 
-		// clause learning
-		seen:    make(map[int]int8),
-		learned: make([]cnf.Lit, 0, 10),
+// growVars allocates every variable up to and including v.
+func (s *Solver) growVars(v int) {
+	s.allocVars(v)
+	for s.numVars < v {
+		s.numVars++
+		s.order.insert(s.numVars)
+	}
+}
+
+// allocVars allocates the state for every variable up to and including v
+// without making them variables of the solver, see NumVars. This is used
+// for internal variables that can't appear in a solution.
+func (s *Solver) allocVars(v int) {
+	for len(s.assigns) <= v {
+		s.assigns = append(s.assigns, triUndef)
+		s.varinfo = append(s.varinfo, varinfo{})
+		s.seen = append(s.seen, 0)
+		s.phases = append(s.phases, triUndef)
+		s.userPolarity = append(s.userPolarity, triUndef)
+		s.watches = append(s.watches, nil, nil)
 	}
 }
 
 // Solve finds a solution for the formula, returning true on satisfiability.
 func (s *Solver) Solve() bool {
+	return s.SolveContext(context.Background()) == ResultSat
+}
+
+// SolveWithAssumptions finds a solution for the formula where each of the
+// given literals is assumed to be true, returning true on satisfiability.
+//
+// The assumptions only hold for this call. Clauses learned while solving
+// are kept, so solving many related problems against the same formula is
+// much cheaper than solving each from scratch. Clauses may be added
+// between calls.
+func (s *Solver) SolveWithAssumptions(lits []cnf.Lit) bool {
+	return s.SolveContext(context.Background(), lits...) == ResultSat
+}
+
+// SolveContext finds a solution for the formula under the given
+// assumptions (see SolveWithAssumptions). Solving stops when the context
+// is cancelled or its deadline is exceeded, in which case ResultUnknown
+// is returned.
+//
+// An interrupted solver is left in a consistent state: clauses learned so
+// far are kept and calling any of the Solve functions again resumes the
+// search.
+func (s *Solver) SolveContext(ctx context.Context, assumptions ...cnf.Lit) Result {
+	for _, l := range assumptions {
+		s.growVars(l.Var())
+	}
+
+	return s.solve(ctx, assumptions)
+}
+
+// solve is SolveContext for assumptions that are already allocated, which
+// may include internal variables.
+func (s *Solver) solve(ctx context.Context, assumptions []cnf.Lit) Result {
 	if s.Trace {
-		s.Tracer.Printf("[TRACE] sat: starting solve()")
+		s.Tracer.Printf("[TRACE] sat: starting solve() with assumptions %s", assumptions)
 	}
 
-	// Check the result. This can be set already by a prior call to Solve
-	// or via the AddClause process.
-	if s.result != satResultUndef {
+	// Make sure the proof is complete whenever we return
+	defer s.proofFlush()
+
+	// Check if the formula is already known to be unsatisfiable. This can
+	// be set already by a prior call to Solve or via the AddClause process.
+	if s.result == ResultUnsat {
 		if s.Trace {
-			s.Tracer.Printf("[TRACE] sat: result is already available: %T", s.result)
+			s.Tracer.Printf("[TRACE] sat: formula is already unsatisfiable")
 		}
 
-		return s.result == satResultSat
+		// No assumptions are needed for the formula to be unsatisfiable
+		s.failed = s.failed[:0]
+		return ResultUnsat
 	}
 
+	// Reset any state from a prior call
+	s.result = ResultUnknown
+	s.trimToDecisionLevel(0)
+	s.assumptions = assumptions
+	s.failed = s.failed[:0]
+	s.budgetStart = s.stats
+
+	done := ctx.Done()
 	for {
 		if s.Trace {
 			s.Tracer.Printf("[TRACE] sat: new iteration. trail: %s", s.trailString())
 		}
 
+		// Stop if we were interrupted. We go back to the top level so
+		// that the solver can be queried and solving can be resumed.
+		if done != nil {
+			select {
+			case <-done:
+				if s.Trace {
+					s.Tracer.Printf("[TRACE] sat: interrupted: %s", ctx.Err())
+				}
+
+				s.trimToDecisionLevel(0)
+				return ResultUnknown
+
+			default:
+			}
+		}
+
+		// Stop if we've used up our budget, resuming works the same as
+		// an interruption.
+		if !s.withinBudget() {
+			if s.Trace {
+				s.Tracer.Printf("[TRACE] sat: budget exhausted")
+			}
+
+			s.trimToDecisionLevel(0)
+			return ResultUnknown
+		}
+
 		conflictC := s.propagate()
-		if conflictC != nil {
+		if conflictC != crefUndef {
+			s.stats.Conflicts++
 			if s.Trace {
 				s.Tracer.Printf("[TRACE] sat: current trail contains negated formula. trail: %s", s.trailString())
-				s.Tracer.Printf("[TRACE] sat: conflict clause: %s", conflictC)
+				s.Tracer.Printf("[TRACE] sat: conflict clause: %s", cnf.Clause(s.arena.lits(conflictC)))
 			}
 
 			// If we have no more decisions within the trail, then we've
@@ -122,14 +294,19 @@ func (s *Solver) Solve() bool {
 					s.Tracer.Printf("[TRACE] sat: at decision level 0. UNSAT")
 				}
 
-				s.result = satResultUnsat
-				return false
+				s.proofAdd(nil)
+				s.result = ResultUnsat
+				return ResultUnsat
 			}
 
 			// Learn
 			level := s.learn(conflictC)
+			lbd := s.lbd(s.learned)
+			s.stats.LearnedClauses++
+			s.stats.LearnedLiterals += int64(len(s.learned))
+			s.proofAdd(s.learned)
 			if s.Trace {
-				s.Tracer.Printf("[TRACE] sat: learned clause: %s", s.learned)
+				s.Tracer.Printf("[TRACE] sat: learned clause: %s (lbd %d)", s.learned, lbd)
 			}
 
 			// Backjump
@@ -146,18 +323,64 @@ func (s *Solver) Solve() bool {
 					"[TRACE] sat: asserting learned literal: %s", s.learned[0])
 			}
 			if len(s.learned) == 1 {
-				s.assertLiteral(s.learned[0], nil)
+				s.assertLiteral(s.learned[0], crefUndef)
 			} else {
-				c := cnf.Clause(make([]cnf.Lit, len(s.learned)))
-				copy(c, s.learned)
+				c := s.addLearned(s.learned, lbd)
+				s.assertLiteral(s.learned[0], c)
+			}
+
+			// Remove learned clauses if it is time to do so
+			if s.shouldReduce() {
+				s.reduceDB()
+			}
+
+			// Restart if our policy tells us to. We keep the learned
+			// clauses so we don't lose any progress.
+			if s.Restart != nil && s.Restart.Conflict(lbd) {
+				if s.Trace {
+					s.Tracer.Printf("[TRACE] sat: restarting")
+				}
 
-				s.clauses = append(s.clauses, c)
-				s.watchClause(c)
-				s.assertLiteral(c[0], c)
+				s.stats.Restarts++
+				s.trimToDecisionLevel(0)
 			}
 		} else {
+			// Assert the next assumption if there are any left. Every
+			// assumption is asserted at its own decision level so that
+			// assumption i is always at level i+1.
+			lit := cnf.LitUndef
+			for lit == cnf.LitUndef && s.decisionLevel() < len(s.assumptions) {
+				p := s.assumptions[s.decisionLevel()]
+				switch s.valueLit(p) {
+				case triTrue:
+					// Already true, introduce an empty decision level
+					s.newDecisionLevel()
+
+				case triFalse:
+					// The assumption can't hold, so the formula is
+					// unsatisfiable under the assumptions.
+					s.analyzeFinal(p)
+					if s.Trace {
+						s.Tracer.Printf(
+							"[TRACE] sat: assumption %s is false. UNSAT, failed assumptions: %s",
+							p, s.failed)
+					}
+
+					s.trimToDecisionLevel(0)
+					return ResultUnsat
+
+				default:
+					lit = p
+				}
+			}
+
 			// Choose a literal to assert.
-			lit := s.selectLiteral()
+			if lit == cnf.LitUndef {
+				lit = s.selectLiteral()
+				if lit != cnf.LitUndef {
+					s.stats.Decisions++
+				}
+			}
 
 			// If it is undef it means there are no more literals which means
 			// we have solved the formula
@@ -166,8 +389,8 @@ func (s *Solver) Solve() bool {
 					s.Tracer.Printf("[TRACE] sat: solver found solution: %s", s.trail)
 				}
 
-				s.result = satResultSat
-				return true
+				s.result = ResultSat
+				return ResultSat
 			}
 
 			// We have a new literal to assert. Create a new decision level
@@ -177,42 +400,40 @@ func (s *Solver) Solve() bool {
 				s.Tracer.Printf("[TRACE] sat: assert: %s (decision)", lit)
 			}
 			s.newDecisionLevel()
-			s.assertLiteral(lit, nil)
+			s.assertLiteral(lit, crefUndef)
 		}
 	}
 }
 
-// selectLiteral returns the next decision literal to assert.
-//
-// NOTE: This logic is horrifyingly naive at the moment and improving
-// this even slightly would probably have some good gains for this solver.
-func (s *Solver) selectLiteral() cnf.Lit {
-	for raw := range s.vars {
-		if _, ok := s.assigns[raw]; !ok {
-			return cnf.NewLit(raw, false)
-		}
-	}
+// withinBudget returns true if none of the budgets for the current solve
+// are exhausted.
+func (s *Solver) withinBudget() bool {
+	return (s.ConflictBudget <= 0 || s.stats.Conflicts-s.budgetStart.Conflicts < s.ConflictBudget) &&
+		(s.DecisionBudget <= 0 || s.stats.Decisions-s.budgetStart.Decisions < s.DecisionBudget) &&
+		(s.PropagationBudget <= 0 || s.stats.Propagations-s.budgetStart.Propagations < s.PropagationBudget)
+}
 
-	return cnf.LitUndef
+// FailedAssumptions returns the assumptions that caused the last solve to
+// be unsatisfiable. The result is a subset of the
+// assumptions that is unsatisfiable together with the formula; it is not
+// necessarily minimal.
+//
+// This is empty if the formula is unsatisfiable regardless of the
+// assumptions. This is only valid if the last solve returned false.
+func (s *Solver) FailedAssumptions() []cnf.Lit {
+	result := make([]cnf.Lit, len(s.failed))
+	copy(result, s.failed)
+	return result
 }
 
 //-------------------------------------------------------------------
 // Private types
 //-------------------------------------------------------------------
 
-// satResult is an enum type for the state of the SAT solver.
-type satResult byte
-
-const (
-	satResultUndef satResult = iota // undefined, solve() valid
-	satResultUnsat                  // unsatisfied
-	satResultSat                    // satisified
-)
-
 // varinfo just stores some basic information about assigned variables
 type varinfo struct {
-	reason cnf.Clause // reason is the clause that caused this assignment
-	level  int        // level is the decision level of this assignment
+	reason cref // reason is the clause that caused this assignment
+	level  int  // level is the decision level of this assignment
 }
 
 // tribool is a tri-state boolean with undefined as the 3rd state.
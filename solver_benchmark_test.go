@@ -5,10 +5,23 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/mitchellh/go-sat/cnf"
 	"github.com/mitchellh/go-sat/dimacs"
 )
 
 func BenchmarkSolver_satLib(b *testing.B) {
+	satlibBenchmark(b, nil)
+}
+
+func BenchmarkSolver_satLibVSIDS(b *testing.B) {
+	satlibBenchmark(b, func(s *Solver) {
+		s.Decision = DecisionVSIDS
+	})
+}
+
+// satlibBenchmark benchmarks the SATLIB problems. If configure is non-nil
+// it is called with every solver prior to adding the formula.
+func satlibBenchmark(b *testing.B, configure func(*Solver)) {
 	// Get the dirs containing our tests, this will be sorted already
 	dirs := satlibDirs(b)
 
@@ -16,12 +29,12 @@ func BenchmarkSolver_satLib(b *testing.B) {
 	for _, d := range dirs {
 		// Run the tests for this dir
 		b.Run(filepath.Base(d), func(b *testing.B) {
-			satlibBenchmarkDir(b, d)
+			satlibBenchmarkDir(b, d, configure)
 		})
 	}
 }
 
-func satlibBenchmarkDir(b *testing.B, dir string) {
+func satlibBenchmarkDir(b *testing.B, dir string, configure func(*Solver)) {
 	// Open the directory so we can read each file
 	dirF, err := os.Open(dir)
 	if err != nil {
@@ -44,7 +57,7 @@ func satlibBenchmarkDir(b *testing.B, dir string) {
 
 		// Test this entry
 		b.Run(entry, func(b *testing.B) {
-			satlibBenchmarkFile(b, filepath.Join(dir, entry))
+			satlibBenchmarkFile(b, filepath.Join(dir, entry), configure)
 		})
 
 		// Run only the threshold number
@@ -55,7 +68,7 @@ func satlibBenchmarkDir(b *testing.B, dir string) {
 	}
 }
 
-func satlibBenchmarkFile(b *testing.B, path string) {
+func satlibBenchmarkFile(b *testing.B, path string, configure func(*Solver)) {
 	// Parse the problem
 	f, err := os.Open(path)
 	if err != nil {
@@ -69,9 +82,89 @@ func satlibBenchmarkFile(b *testing.B, path string) {
 	}
 
 	b.ResetTimer()
+	var stats Stats
 	for i := 0; i < b.N; i++ {
 		s := New()
+		if configure != nil {
+			configure(s)
+		}
 		s.AddFormula(p.Formula)
 		s.Solve()
+		stats = s.Stats()
+	}
+
+	// Report the effort of the solver so that regressions in the search
+	// itself can be found separately from raw performance.
+	b.ReportMetric(float64(stats.Conflicts), "conflicts/op")
+	b.ReportMetric(float64(stats.Decisions), "decisions/op")
+	b.ReportMetric(float64(stats.Propagations), "propagations/op")
+}
+
+func BenchmarkSolver_satLibLuby(b *testing.B) {
+	satlibBenchmark(b, func(s *Solver) {
+		s.Decision = DecisionVSIDS
+		s.Restart = &LubyRestart{}
+	})
+}
+
+func BenchmarkSolver_satLibGlucose(b *testing.B) {
+	satlibBenchmark(b, func(s *Solver) {
+		s.Decision = DecisionVSIDS
+		s.Restart = &GlucoseRestart{}
+	})
+}
+
+func BenchmarkSolver_satLibMinimization(b *testing.B) {
+	satlibBenchmark(b, func(s *Solver) {
+		s.Decision = DecisionVSIDS
+		s.Restart = &LubyRestart{}
+		s.ClauseMinimization = true
+	})
+}
+
+// Benchmark a hard unsatisfiable problem where the time is dominated by
+// the per-variable state of the solver: propagation and conflict analysis.
+func BenchmarkSolver_pigeonhole(b *testing.B) {
+	ints := pigeonhole(7)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s := New()
+		s.Decision = DecisionVSIDS
+		s.Restart = &LubyRestart{}
+		s.AddFormula(cnf.NewFormulaFromInts(ints))
+		if s.Solve() {
+			b.Fatal("expected false, got true")
+		}
+	}
+}
+
+// Benchmark unit propagation alone on a long chain of implications:
+// x1 -> x2 -> ... -> xN.
+func BenchmarkSolver_propagate(b *testing.B) {
+	const n = 10000
+
+	ints := make([][]int, 0, n)
+	for v := 1; v < n; v++ {
+		ints = append(ints, []int{-v, v + 1})
+	}
+
+	s := New()
+	s.AddFormula(cnf.NewFormulaFromInts(ints))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.newDecisionLevel()
+		s.assertLiteral(cnf.NewLitInt(1), crefUndef)
+		if c := s.propagate(); c != crefUndef {
+			b.Fatalf("conflict: %d", c)
+		}
+		if len(s.trail) != n {
+			b.Fatalf("bad: %d", len(s.trail))
+		}
+
+		s.trimToDecisionLevel(0)
 	}
 }
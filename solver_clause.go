@@ -0,0 +1,139 @@
+package sat
+
+import (
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+// This file contains the clause storage for the solver.
+
+// cref is a reference to a clause in a clauseArena. It is the index of the
+// header of the clause within the arena.
+type cref int32
+
+// crefUndef is the reference to no clause. This is the reason for
+// decisions and for assignments at level 0 that came from unit clauses.
+const crefUndef cref = -1
+
+const (
+	// clauseHeader is the number of words preceding the literals of every
+	// clause in the arena: the number of literals and the flags.
+	clauseHeader = 2
+
+	clauseLearned   = 1 << 0 // clause was learned during conflict analysis
+	clauseDeleted   = 1 << 1 // clause was deleted and is garbage
+	clauseRelocated = 1 << 2 // clause was moved to another arena
+)
+
+// clauseArena stores every clause of the solver contiguously in a single
+// slice, which keeps the clauses close together in memory and means that
+// the garbage collector only ever sees a single object regardless of the
+// number of clauses. Clauses are referenced by their index (cref).
+//
+// Deleted clauses aren't removed right away; the space they use is
+// reclaimed by moving every live clause into a new arena (see
+// Solver.garbageCollect).
+type clauseArena struct {
+	data   []cnf.Lit
+	wasted int // words used by deleted clauses
+}
+
+// alloc stores a copy of the clause made up of lits and returns its
+// reference.
+func (a *clauseArena) alloc(lits []cnf.Lit, learned bool) cref {
+	var flags cnf.Lit
+	if learned {
+		flags |= clauseLearned
+	}
+
+	c := cref(len(a.data))
+	a.data = append(a.data, cnf.Lit(len(lits)), flags)
+	a.data = append(a.data, lits...)
+	return c
+}
+
+// lits returns the literals of the clause c. The result refers directly to
+// the arena so changes to the literals change the clause. It is only valid
+// until the next call to alloc.
+func (a *clauseArena) lits(c cref) []cnf.Lit {
+	start := int(c) + clauseHeader
+	end := start + int(a.data[c])
+	return a.data[start:end:end]
+}
+
+// learned returns true if the clause c is a learned clause.
+func (a *clauseArena) learned(c cref) bool {
+	return a.data[c+1]&clauseLearned != 0
+}
+
+// deleted returns true if the clause c was deleted with free.
+func (a *clauseArena) deleted(c cref) bool {
+	return a.data[c+1]&clauseDeleted != 0
+}
+
+// free marks the clause c as deleted. The clause must no longer be
+// referenced once the arena is garbage collected.
+func (a *clauseArena) free(c cref) {
+	a.data[c+1] |= clauseDeleted
+	a.wasted += clauseHeader + int(a.data[c])
+}
+
+// reloc moves the clause c into the arena to and returns the reference to
+// the clause in that arena. Relocating the same clause more than once
+// returns the same result, so every reference to a clause can be relocated
+// independently.
+func (a *clauseArena) reloc(c cref, to *clauseArena) cref {
+	// A relocated clause stores its new reference in place of its first
+	// literal. Every clause has at least two literals.
+	if a.data[c+1]&clauseRelocated != 0 {
+		return cref(a.data[c+clauseHeader])
+	}
+
+	result := to.alloc(a.lits(c), a.learned(c))
+	a.data[c+1] |= clauseRelocated
+	a.data[c+clauseHeader] = cnf.Lit(result)
+	return result
+}
+
+// garbageCollect reclaims the space used by deleted clauses by moving every
+// live clause to a new arena. Every clause reference held by the solver is
+// updated.
+func (s *Solver) garbageCollect() {
+	to := clauseArena{
+		data: make([]cnf.Lit, 0, len(s.arena.data)-s.arena.wasted),
+	}
+
+	// Move the clauses in the order they were added so that they remain
+	// in the same order in the new arena.
+	for i, c := range s.clauses {
+		s.clauses[i] = s.arena.reloc(c, &to)
+	}
+	for i := range s.learnedClauses {
+		lc := &s.learnedClauses[i]
+		lc.ref = s.arena.reloc(lc.ref, &to)
+	}
+
+	// Watches never refer to deleted clauses (see reduceDB)
+	for i := range s.watches {
+		ws := s.watches[i]
+		for j := range ws {
+			ws[j].ref = s.arena.reloc(ws[j].ref, &to)
+		}
+	}
+
+	// Only the reasons of assigned variables are meaningful. They are
+	// never deleted since the clauses are locked.
+	for _, l := range s.trail {
+		info := &s.varinfo[l.Var()]
+		if info.reason != crefUndef {
+			info.reason = s.arena.reloc(info.reason, &to)
+		}
+	}
+
+	if s.Trace {
+		s.Tracer.Printf(
+			"[TRACE] sat: garbage collected clauses: size %d to %d",
+			len(s.arena.data), len(to.data))
+	}
+
+	s.arena = to
+}
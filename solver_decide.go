@@ -0,0 +1,283 @@
+package sat
+
+import (
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+// This file contains the decision heuristics for the solver.
+
+// DecisionHeuristic is the heuristic used to select the next decision
+// variable when no propagation is possible.
+type DecisionHeuristic byte
+
+const (
+	// DecisionNaive selects the first unassigned variable that is found.
+	// This is the original behavior of the solver and is kept around for
+	// comparison purposes.
+	DecisionNaive DecisionHeuristic = iota
+
+	// DecisionVSIDS selects the unassigned variable with the highest
+	// activity. Activity is bumped for every variable that takes part in
+	// conflict analysis and decays over time, so the solver focuses on the
+	// variables that were involved in recent conflicts (similar to the
+	// heuristic used by Minisat).
+	DecisionVSIDS
+)
+
+// Polarity is the default value that decision variables are assigned.
+type Polarity byte
+
+const (
+	// PolarityPositive assigns decision variables to true.
+	PolarityPositive Polarity = iota
+
+	// PolarityNegative assigns decision variables to false.
+	PolarityNegative
+
+	// PolarityRandom assigns decision variables to a random value.
+	PolarityRandom
+)
+
+// randomSeed is the seed used for any randomness in the solver if
+// Options.Seed isn't set.
+const randomSeed = 91648253
+
+const (
+	// varDecay is the factor that activity decays by after each conflict.
+	// Rather than decaying every variable we grow the increment by the
+	// inverse, which is equivalent but constant time.
+	varDecay = 0.95
+
+	// varRescale is the activity limit at which all activities are
+	// scaled down to avoid overflowing float64.
+	varRescale = 1e100
+)
+
+// selectLiteral returns the next decision literal to assert.
+func (s *Solver) selectLiteral() cnf.Lit {
+	if s.RandomDecisionFreq > 0 && s.random().Float64() < s.RandomDecisionFreq {
+		if l := s.selectLiteralRandom(); l != cnf.LitUndef {
+			return l
+		}
+	}
+
+	switch s.Decision {
+	case DecisionVSIDS:
+		return s.selectLiteralVSIDS()
+
+	default:
+		return s.selectLiteralNaive()
+	}
+}
+
+// selectLiteralNaive picks the first unassigned variable.
+//
+// NOTE: This logic is horrifyingly naive and is only kept around so that
+// it can be compared against the other heuristics.
+func (s *Solver) selectLiteralNaive() cnf.Lit {
+	for v := 1; v <= s.numVars; v++ {
+		if s.assigns[v] == triUndef {
+			return s.decisionLit(v)
+		}
+	}
+
+	return cnf.LitUndef
+}
+
+// selectLiteralRandom picks a random variable. If the variable is already
+// assigned then this returns LitUndef and the regular heuristic should be
+// used instead.
+func (s *Solver) selectLiteralRandom() cnf.Lit {
+	if s.numVars == 0 {
+		return cnf.LitUndef
+	}
+
+	v := s.random().Intn(s.numVars) + 1
+	if s.assigns[v] != triUndef {
+		return cnf.LitUndef
+	}
+
+	return s.decisionLit(v)
+}
+
+// selectLiteralVSIDS picks the unassigned variable with the highest activity.
+func (s *Solver) selectLiteralVSIDS() cnf.Lit {
+	for !s.order.empty() {
+		// Internal variables above numVars are only ever assumed
+		v := s.order.removeMax()
+		if v <= s.numVars && s.assigns[v] == triUndef {
+			return s.decisionLit(v)
+		}
+	}
+
+	return cnf.LitUndef
+}
+
+// decisionLit returns the literal to assert when deciding on the variable
+// v. A polarity set with SetPolarity takes precedence, followed by the
+// saved phase (if phase saving is enabled) and finally the default Polarity.
+func (s *Solver) decisionLit(v int) cnf.Lit {
+	if value := s.userPolarity[v]; value != triUndef {
+		return cnf.NewLit(v, value == triFalse)
+	}
+
+	if s.PhaseSaving {
+		if value := s.phases[v]; value != triUndef {
+			return cnf.NewLit(v, value == triFalse)
+		}
+	}
+
+	switch s.Polarity {
+	case PolarityNegative:
+		return cnf.NewLit(v, true)
+
+	case PolarityRandom:
+		return cnf.NewLit(v, s.random().Intn(2) == 1)
+
+	default:
+		return cnf.NewLit(v, false)
+	}
+}
+
+// SetPolarity sets the preferred value for the variable v when it is
+// chosen as a decision variable. This takes precedence over the phase
+// saving and default polarity settings. The variable is allocated if it
+// isn't already.
+func (s *Solver) SetPolarity(v int, value bool) {
+	s.growVars(v)
+	s.userPolarity[v] = boolToTri(value)
+}
+
+// varOrder keeps track of variable activity and orders the variables by
+// that activity using a binary heap so that the most active variable can
+// be found quickly.
+//
+// Assigned variables may remain in the heap; they are skipped when they
+// are removed. Variables must be reinserted when they become unassigned.
+type varOrder struct {
+	activity []float64 // activity of each variable, indexed by variable
+	inc      float64   // amount to bump activity by
+
+	heap    []int // heap of variables
+	indices []int // index of each variable in heap, -1 if not present
+}
+
+func newVarOrder() *varOrder {
+	return &varOrder{inc: 1}
+}
+
+// grow makes sure the variable v can be stored in the order.
+func (o *varOrder) grow(v int) {
+	for len(o.activity) <= v {
+		o.activity = append(o.activity, 0)
+		o.indices = append(o.indices, -1)
+	}
+}
+
+// bump increases the activity of the variable v.
+func (o *varOrder) bump(v int) {
+	o.activity[v] += o.inc
+	if o.activity[v] > varRescale {
+		for k := range o.activity {
+			o.activity[k] *= 1 / varRescale
+		}
+
+		o.inc *= 1 / varRescale
+	}
+
+	// Activity only ever increases so we only have to move up
+	if idx := o.indices[v]; idx >= 0 {
+		o.up(idx)
+	}
+}
+
+// decay decays the activity of all variables.
+func (o *varOrder) decay() {
+	o.inc *= 1 / varDecay
+}
+
+// empty returns true if there are no variables in the heap.
+func (o *varOrder) empty() bool {
+	return len(o.heap) == 0
+}
+
+// contains returns true if the variable is in the heap.
+func (o *varOrder) contains(v int) bool {
+	return v < len(o.indices) && o.indices[v] >= 0
+}
+
+// insert inserts the variable v into the heap if it isn't already present.
+func (o *varOrder) insert(v int) {
+	if o.contains(v) {
+		return
+	}
+
+	o.grow(v)
+	o.indices[v] = len(o.heap)
+	o.heap = append(o.heap, v)
+	o.up(len(o.heap) - 1)
+}
+
+// removeMax removes and returns the variable with the highest activity.
+func (o *varOrder) removeMax() int {
+	v := o.heap[0]
+	last := len(o.heap) - 1
+	o.heap[0] = o.heap[last]
+	o.indices[o.heap[0]] = 0
+	o.heap = o.heap[:last]
+	o.indices[v] = -1
+	if len(o.heap) > 1 {
+		o.down(0)
+	}
+
+	return v
+}
+
+// less is the ordering of the heap. Ties are broken by the variable
+// so that the order is predictable.
+func (o *varOrder) less(a, b int) bool {
+	actA, actB := o.activity[a], o.activity[b]
+	return actA > actB || (actA == actB && a < b)
+}
+
+func (o *varOrder) up(i int) {
+	v := o.heap[i]
+	for i > 0 {
+		parent := (i - 1) >> 1
+		if !o.less(v, o.heap[parent]) {
+			break
+		}
+
+		o.heap[i] = o.heap[parent]
+		o.indices[o.heap[i]] = i
+		i = parent
+	}
+
+	o.heap[i] = v
+	o.indices[v] = i
+}
+
+func (o *varOrder) down(i int) {
+	v := o.heap[i]
+	for {
+		child := 2*i + 1
+		if child >= len(o.heap) {
+			break
+		}
+
+		if right := child + 1; right < len(o.heap) && o.less(o.heap[right], o.heap[child]) {
+			child = right
+		}
+
+		if !o.less(o.heap[child], v) {
+			break
+		}
+
+		o.heap[i] = o.heap[child]
+		o.indices[o.heap[i]] = i
+		i = child
+	}
+
+	o.heap[i] = v
+	o.indices[v] = i
+}
@@ -0,0 +1,148 @@
+package sat
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+func TestVarOrder(t *testing.T) {
+	o := newVarOrder()
+	for _, v := range []int{5, 3, 1, 4, 2} {
+		o.insert(v)
+	}
+
+	// Bump so that we have a known order
+	o.bump(4)
+	o.bump(4)
+	o.bump(2)
+	o.decay()
+	o.bump(5)
+
+	var result []int
+	for !o.empty() {
+		result = append(result, o.removeMax())
+	}
+
+	expected := []int{4, 5, 2, 1, 3}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestVarOrder_rescale(t *testing.T) {
+	o := newVarOrder()
+	o.insert(1)
+	o.insert(2)
+	o.inc = varRescale
+
+	o.bump(1)
+	o.bump(1)
+	if o.activity[1] > varRescale {
+		t.Fatalf("activity not rescaled: %v", o.activity[1])
+	}
+	if v := o.removeMax(); v != 1 {
+		t.Fatalf("bad: %d", v)
+	}
+}
+
+func TestSolverSelectLiteral_vsids(t *testing.T) {
+	s := New()
+	s.Decision = DecisionVSIDS
+	s.AddFormula(cnf.NewFormulaFromInts([][]int{
+		[]int{1, 2, 3},
+		[]int{-1, -2, -3},
+	}))
+
+	s.order.bump(3)
+
+	// The most active variable should be chosen
+	if l := s.selectLiteral(); l.Var() != 3 {
+		t.Fatalf("bad: %s", l)
+	}
+
+	// Assigned variables should be skipped
+	s.newDecisionLevel()
+	s.assertLiteral(cnf.NewLitInt(1), crefUndef)
+	if l := s.selectLiteral(); l.Var() != 2 {
+		t.Fatalf("bad: %s", l)
+	}
+}
+
+func TestSolverDecisionLit(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Polarity Polarity
+		User     map[int]bool
+		Result   int
+	}{
+		{
+			"positive",
+			PolarityPositive,
+			nil,
+			3,
+		},
+
+		{
+			"negative",
+			PolarityNegative,
+			nil,
+			-3,
+		},
+
+		{
+			"user over default",
+			PolarityNegative,
+			map[int]bool{3: true},
+			3,
+		},
+
+		{
+			"user negative",
+			PolarityPositive,
+			map[int]bool{3: false},
+			-3,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d-%s", i, tc.Name), func(t *testing.T) {
+			s := New()
+			s.growVars(3)
+			s.Polarity = tc.Polarity
+			for v, value := range tc.User {
+				s.SetPolarity(v, value)
+			}
+
+			if l := s.decisionLit(3); l.Int() != tc.Result {
+				t.Fatalf("bad: %s", l)
+			}
+		})
+	}
+}
+
+func TestSolverDecisionLit_phaseSaving(t *testing.T) {
+	s := New()
+	s.growVars(5)
+	s.PhaseSaving = true
+
+	s.newDecisionLevel()
+	s.assertLiteral(cnf.NewLitInt(-3), crefUndef)
+	s.assertLiteral(cnf.NewLitInt(4), crefUndef)
+	s.trimToDecisionLevel(0)
+
+	if l := s.decisionLit(3); l.Int() != -3 {
+		t.Fatalf("bad: %s", l)
+	}
+	if l := s.decisionLit(4); l.Int() != 4 {
+		t.Fatalf("bad: %s", l)
+	}
+
+	// Never assigned falls back to the default polarity
+	s.Polarity = PolarityNegative
+	if l := s.decisionLit(5); l.Int() != -5 {
+		t.Fatalf("bad: %s", l)
+	}
+}
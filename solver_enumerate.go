@@ -0,0 +1,165 @@
+package sat
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+// This file contains model enumeration (AllSAT) for the solver.
+
+// ErrBudget is returned by Enumerate if a budget was exhausted before the
+// enumeration completed.
+var ErrBudget = errors.New("sat: budget exhausted")
+
+// Enumerate calls fn with every solution of the formula. Enumeration stops
+// early if fn returns false.
+//
+// If vars is non-nil then solutions are projected onto those variables:
+// every model passed to fn only contains vars and two solutions that only
+// differ in other variables are only reported once. If vars is nil then
+// every variable allocated when Enumerate is called is used.
+//
+// Each solution is excluded from the search by adding a clause that blocks
+// it. The blocking clauses, and any clauses learned from them, are removed
+// when Enumerate returns so the solver can be used as before, including
+// for another enumeration. Enumerate doesn't allocate any variables other
+// than vars.
+//
+// Budgets apply to the search for each solution. If ctx is cancelled then
+// ctx.Err() is returned and if a budget is exhausted then ErrBudget is
+// returned. In either case fn has already been called with every solution
+// found so far.
+func (s *Solver) Enumerate(ctx context.Context, vars []int, fn func(model map[int]bool) bool) error {
+	if vars == nil {
+		vars = make([]int, s.numVars)
+		for i := range vars {
+			vars[i] = i + 1
+		}
+	}
+	for _, v := range vars {
+		s.growVars(v)
+	}
+
+	// Every blocking clause contains the negation of an activation
+	// literal, which we assume for every solve. The activation variable is
+	// internal: it is above NumVars so it is never part of a solution, and
+	// it is reused by every enumeration.
+	if s.enumVar <= s.numVars {
+		s.enumVar = s.numVars + 1
+		s.allocVars(s.enumVar)
+	}
+	act := cnf.NewLit(s.enumVar, false)
+	defer s.removeBlocking(act)
+
+	block := make(cnf.Clause, 0, len(vars)+1)
+	for {
+		switch s.solve(ctx, []cnf.Lit{act}) {
+		case ResultUnsat:
+			return nil
+
+		case ResultUnknown:
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			return ErrBudget
+		}
+
+		model := make(map[int]bool, len(vars))
+		block = append(block[:0], act.Neg())
+		for _, v := range vars {
+			value := s.assigns[v] == triTrue
+			model[v] = value
+			block = append(block, cnf.NewLit(v, value))
+		}
+
+		if s.Trace {
+			s.Tracer.Printf("[TRACE] sat: enumerate: blocking solution: %s", block)
+		}
+
+		if !fn(model) {
+			return nil
+		}
+
+		s.addClause(block)
+	}
+}
+
+// removeBlocking removes every clause containing the negation of the
+// activation literal act. These are the blocking clauses added by
+// Enumerate and the clauses learned from them, since act is always the
+// first decision while enumerating.
+func (s *Solver) removeBlocking(act cnf.Lit) {
+	s.trimToDecisionLevel(0)
+	if s.result != ResultUnsat {
+		s.result = ResultUnknown
+	}
+	s.failed = s.failed[:0]
+
+	// The negation of act may have been learned as a unit. No clause
+	// contains act so nothing was implied by it and it can be removed
+	// from the trail directly.
+	if v := act.Var(); s.assigns[v] != triUndef {
+		j := 0
+		for i, l := range s.trail {
+			if l.Var() == v {
+				if i < s.qhead {
+					s.qhead--
+				}
+
+				continue
+			}
+
+			s.trail[j] = l
+			j++
+		}
+
+		s.trail = s.trail[:j]
+		s.assigns[v] = triUndef
+	}
+
+	neg := act.Neg()
+	j := 0
+	for _, c := range s.clauses {
+		if containsLit(s.arena.lits(c), neg) {
+			s.arena.free(c)
+			continue
+		}
+
+		s.clauses[j] = c
+		j++
+	}
+	s.clauses = s.clauses[:j]
+
+	j = 0
+	for _, lc := range s.learnedClauses {
+		if lits := s.arena.lits(lc.ref); containsLit(lits, neg) {
+			s.proofDelete(lits)
+			s.arena.free(lc.ref)
+			s.stats.DeletedClauses++
+			continue
+		}
+
+		s.learnedClauses[j] = lc
+		j++
+	}
+	s.learnedClauses = s.learnedClauses[:j]
+
+	s.cleanWatches()
+	if s.arena.wasted > len(s.arena.data)/2 {
+		s.garbageCollect()
+	}
+}
+
+// containsLit returns true if lits contains the literal l.
+func containsLit(lits []cnf.Lit, l cnf.Lit) bool {
+	for _, current := range lits {
+		if current == l {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,240 @@
+package sat
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+func TestSolverEnumerate(t *testing.T) {
+	cases := []struct {
+		Name    string
+		Formula [][]int
+		Vars    []int
+		Models  []string
+	}{
+		{
+			"unsat",
+			[][]int{
+				[]int{1},
+				[]int{-1},
+			},
+			nil,
+			nil,
+		},
+
+		{
+			"single",
+			[][]int{
+				[]int{1},
+				[]int{-1, 2},
+			},
+			nil,
+			[]string{"[1 2]"},
+		},
+
+		{
+			"or",
+			[][]int{
+				[]int{1, 2, 3},
+			},
+			nil,
+			[]string{
+				"[-1 -2 3]", "[-1 2 -3]", "[-1 2 3]", "[1 -2 -3]",
+				"[1 -2 3]", "[1 2 -3]", "[1 2 3]",
+			},
+		},
+
+		{
+			"xor",
+			[][]int{
+				[]int{1, 2},
+				[]int{-1, -2},
+			},
+			nil,
+			[]string{"[-1 2]", "[1 -2]"},
+		},
+
+		{
+			"projected",
+			[][]int{
+				[]int{1, 2, 3},
+				[]int{-1, -2},
+			},
+			[]int{1, 2},
+			[]string{"[-1 -2]", "[-1 2]", "[1 -2]"},
+		},
+
+		{
+			"projected unconstrained",
+			[][]int{
+				[]int{1, 2},
+			},
+			[]int{3},
+			[]string{"[-3]", "[3]"},
+		},
+
+		{
+			"projected empty",
+			[][]int{
+				[]int{1, 2},
+			},
+			[]int{},
+			[]string{"[]"},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d-%s", i, tc.Name), func(t *testing.T) {
+			s := New()
+			s.AddFormula(cnf.NewFormulaFromInts(tc.Formula))
+
+			// Record each model as its literals ordered by variable
+			var models []string
+			err := s.Enumerate(context.Background(), tc.Vars, func(m map[int]bool) bool {
+				vars := make([]int, 0, len(m))
+				for v := range m {
+					vars = append(vars, v)
+				}
+				sort.Ints(vars)
+
+				lits := make([]int, len(vars))
+				for j, v := range vars {
+					lits[j] = v
+					if !m[v] {
+						lits[j] = -v
+					}
+				}
+
+				models = append(models, fmt.Sprint(lits))
+				return true
+			})
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			sort.Strings(models)
+			if !reflect.DeepEqual(models, tc.Models) {
+				t.Fatalf("bad: %#v", models)
+			}
+		})
+	}
+}
+
+func TestSolverEnumerate_stop(t *testing.T) {
+	s := New()
+	s.AddFormula(cnf.NewFormulaFromInts([][]int{
+		[]int{1, 2, 3},
+	}))
+
+	count := 0
+	err := s.Enumerate(context.Background(), nil, func(map[int]bool) bool {
+		count++
+		return count < 3
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if count != 3 {
+		t.Fatalf("bad: %d", count)
+	}
+}
+
+func TestSolverEnumerate_reuse(t *testing.T) {
+	s := New()
+	s.AddFormula(cnf.NewFormulaFromInts([][]int{
+		[]int{1, 2},
+	}))
+
+	for i := 0; i < 2; i++ {
+		count := 0
+		err := s.Enumerate(context.Background(), []int{1, 2}, func(map[int]bool) bool {
+			count++
+			return true
+		})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if count != 3 {
+			t.Fatalf("bad: %d", count)
+		}
+	}
+
+	// The blocking clauses shouldn't affect the solver
+	if !s.Solve() {
+		t.Fatal("expected true, got false")
+	}
+}
+
+func TestSolverEnumerate_vars(t *testing.T) {
+	s := New()
+	s.AddFormula(cnf.NewFormulaFromInts([][]int{
+		[]int{1, 2},
+		[]int{-1, -2},
+	}))
+	clauses := len(s.clauses)
+
+	for i := 0; i < 2; i++ {
+		err := s.Enumerate(context.Background(), nil, func(map[int]bool) bool {
+			return true
+		})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// The activation variable is hidden and the blocking clauses are gone
+	if s.NumVars() != 2 {
+		t.Fatalf("bad: %d", s.NumVars())
+	}
+	if len(s.clauses) != clauses || len(s.learnedClauses) != 0 {
+		t.Fatalf("bad: %d %d", len(s.clauses), len(s.learnedClauses))
+	}
+
+	if !s.Solve() {
+		t.Fatal("expected true, got false")
+	}
+	if m := s.Assignments(); len(m) != 2 || m[1] == m[2] {
+		t.Fatalf("bad: %#v", m)
+	}
+}
+
+func TestSolverEnumerate_cancel(t *testing.T) {
+	s := New()
+	s.AddFormula(cnf.NewFormulaFromInts([][]int{
+		[]int{1, 2, 3},
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	count := 0
+	err := s.Enumerate(ctx, nil, func(map[int]bool) bool {
+		count++
+		cancel()
+		return true
+	})
+	if err != context.Canceled {
+		t.Fatalf("err: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("bad: %d", count)
+	}
+}
+
+func TestSolverEnumerate_budget(t *testing.T) {
+	s := New()
+	s.DecisionBudget = 1
+	s.AddFormula(cnf.NewFormulaFromInts([][]int{
+		[]int{1, 2, 3},
+	}))
+
+	err := s.Enumerate(context.Background(), nil, func(map[int]bool) bool {
+		return true
+	})
+	if err != ErrBudget {
+		t.Fatalf("err: %v", err)
+	}
+}
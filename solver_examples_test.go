@@ -1,12 +1,14 @@
 package sat
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/mitchellh/go-sat/cnf"
 )
 
-func ExampleSolve() {
+func ExampleSolver_Solve() {
 	// Consider the example formula already in CNF.
 	//
 	// ( ¬x1 ∨ ¬x3 ∨ ¬x4 ) ∧ ( x2 ∨ x3 ∨ ¬x4 ) ∧
@@ -52,3 +54,47 @@ func ExampleSolve() {
 	//   x3 = true
 	//   x4 = false
 }
+
+func ExampleSolver_SolveContext() {
+	// ( x1 ∨ x2 ) ∧ ( ¬x1 ∨ x2 ) ∧ ( x1 ∨ ¬x2 )
+	formula := cnf.NewFormulaFromInts([][]int{
+		[]int{1, 2},
+		[]int{-1, 2},
+		[]int{1, -2},
+	})
+
+	s := New()
+	s.AddFormula(formula)
+
+	// Give up if solving takes longer than a second. If this happens,
+	// the result is ResultUnknown and solving can be resumed later.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	result := s.SolveContext(ctx)
+	fmt.Printf("Result: %s\n", result)
+	// Output:
+	// Result: SATISFIABLE
+}
+
+func ExampleSolver_Enumerate() {
+	// ( x1 ∨ x2 ) ∧ ( ¬x1 ∨ ¬x2 ∨ x3 )
+	formula := cnf.NewFormulaFromInts([][]int{
+		[]int{1, 2},
+		[]int{-1, -2, 3},
+	})
+
+	s := New()
+	s.AddFormula(formula)
+
+	// Count the solutions, only considering x1 and x2
+	count := 0
+	s.Enumerate(context.Background(), []int{1, 2}, func(model map[int]bool) bool {
+		count++
+		return true
+	})
+
+	fmt.Printf("Solutions: %d\n", count)
+	// Output:
+	// Solutions: 3
+}
@@ -8,7 +8,7 @@ import (
 
 // AddFormula adds the given formula to the solver.
 //
-// This can only be called before Solve() is called.
+// This can be called before Solve() or between calls to Solve().
 func (s *Solver) AddFormula(f cnf.Formula) {
 	for _, c := range f {
 		s.AddClause(c)
@@ -17,8 +17,30 @@ func (s *Solver) AddFormula(f cnf.Formula) {
 
 // AddClause adds a Clause to solve to the solver.
 //
-// This can only be called before Solve() is called.
+// This can be called before Solve() or between calls to Solve(). Adding a
+// clause discards any solution found by a prior call to Solve(). Every
+// variable up to the largest variable in the clause is allocated.
 func (s *Solver) AddClause(c cnf.Clause) {
+	for _, l := range c {
+		s.growVars(l.Var())
+	}
+
+	s.addClause(c)
+}
+
+// addClause is AddClause for a clause whose variables are already
+// allocated, which may include internal variables.
+func (s *Solver) addClause(c cnf.Clause) {
+	// If we're already unsatisfiable, adding clauses won't change that
+	if s.result == ResultUnsat {
+		return
+	}
+
+	// Go back to the top level so we only see permanent assignments.
+	// This removes the solution of any prior solve.
+	s.result = ResultUnknown
+	s.trimToDecisionLevel(0)
+
 	// Get the actual slice since we'll be modifying this directly.
 	// The API docs say not to but its part of our package and we know
 	// what we're doing. :)
@@ -88,32 +110,38 @@ func (s *Solver) AddClause(c cnf.Clause) {
 			s.Tracer.Printf("[TRACE] sat: addClause: empty clause, forcing unsat")
 		}
 
-		s.result = satResultUnsat
+		s.proofAdd(nil)
+		s.result = ResultUnsat
+		s.failed = s.failed[:0]
 		return
 	}
 
-	// Track the available decision variables
-	for _, l := range lits {
-		s.vars[l.Var()] = struct{}{}
-	}
-
 	// If this is a single literal clause then we assert it cause it must be
 	if len(lits) == 1 {
 		if s.Trace {
 			s.Tracer.Printf("[TRACE] sat: addClause: single literal clause, asserting %s", lits[0])
 		}
 
-		s.assertLiteral(lits[0], nil)
+		s.assertLiteral(lits[0], crefUndef)
 
-		// Do unit propagation since this may solve already clauses
-		s.propagate()
+		// Do unit propagation since this may solve already clauses. A
+		// conflict here means the formula can't be satisfied.
+		if s.propagate() != crefUndef {
+			if s.Trace {
+				s.Tracer.Printf("[TRACE] sat: addClause: conflict at level 0, forcing unsat")
+			}
+
+			s.proofAdd(nil)
+			s.result = ResultUnsat
+			s.failed = s.failed[:0]
+		}
 
 		// We also don't add this clause since we just asserted the value
 		return
 	}
 
 	// Add it to our formula
-	c = cnf.Clause(lits)
-	s.clauses = append(s.clauses, c)
-	s.watchClause(c)
+	ref := s.arena.alloc(lits, false)
+	s.clauses = append(s.clauses, ref)
+	s.watchClause(ref)
 }
@@ -5,13 +5,15 @@ import (
 )
 
 // learn performs the clause learning process after a conflict is found.
-// This returns the learned clause as well as the level to backjump to.
-func (s *Solver) learn(c cnf.Clause) int {
+// The learned clause is stored in s.learned and the level to backjump to
+// is returned.
+func (s *Solver) learn(conflict cref) int {
 	// Determine our learned clause
 	pathC := 0
 	s.learned = s.learned[:1]
 	p := cnf.LitUndef
 	idx := len(s.trail) - 1
+	c := s.arena.lits(conflict)
 	for {
 		j := 0
 		if p != cnf.LitUndef {
@@ -24,6 +26,7 @@ func (s *Solver) learn(c cnf.Clause) int {
 			qLevel := s.level(qVar)
 			if s.seen[qVar] == 0 && qLevel > 0 {
 				s.seen[qVar] = 1
+				s.order.bump(qVar)
 				if qLevel >= s.decisionLevel() {
 					pathC++
 				} else {
@@ -39,16 +42,25 @@ func (s *Solver) learn(c cnf.Clause) int {
 		idx--
 
 		p = s.trail[idx+1]
-		c = s.varinfo[p.Var()].reason
 		s.seen[p.Var()] = 0
 
 		pathC--
 		if pathC <= 0 {
 			break
 		}
+
+		c = s.arena.lits(s.varinfo[p.Var()].reason)
 	}
 	s.learned[0] = p.Neg()
 
+	// Remove redundant literals. Minimization marks additional literals
+	// as seen which must be cleared along with the learned clause.
+	toClear := s.learned
+	if s.ClauseMinimization {
+		s.minimize()
+		toClear = s.toClear
+	}
+
 	// Determine the level to backjump to. This is simply the maximum
 	// level represented in our learned clause.
 	backjumpLevel := 0
@@ -68,9 +80,147 @@ func (s *Solver) learn(c cnf.Clause) int {
 
 	// Clear seen for learned clause so that learning can visit them
 	// again on the next go-around.
-	for _, l := range s.learned {
+	for _, l := range toClear {
 		s.seen[l.Var()] = 0
 	}
 
+	// Decay activity so that variables in recent conflicts are preferred
+	s.order.decay()
+
 	return backjumpLevel
 }
+
+// minimize removes the redundant literals from the learned clause. A
+// literal is redundant if its negation is implied by the negations of the
+// other literals in the clause, which is determined by recursively
+// following the reasons of its assignment. The asserting literal (the first
+// literal) is always kept.
+//
+// Every literal of the clause must be marked as seen. Every literal that is
+// marked while minimizing is added to s.toClear, along with the literals of
+// the original clause.
+func (s *Solver) minimize() {
+	s.toClear = append(s.toClear[:0], s.learned...)
+
+	// The levels in the clause as a bitmask. A literal can only be
+	// redundant if every literal it depends on is at one of those levels,
+	// so this lets us fail early in most cases.
+	var levels uint32
+	for _, l := range s.learned[1:] {
+		levels |= s.abstractLevel(l.Var())
+	}
+
+	j := 1
+	for _, l := range s.learned[1:] {
+		if s.varinfo[l.Var()].reason == crefUndef || !s.redundant(l, levels) {
+			s.learned[j] = l
+			j++
+		}
+	}
+
+	s.stats.MinimizedLiterals += int64(len(s.learned) - j)
+	s.learned = s.learned[:j]
+}
+
+// redundant returns true if the literal p of the learned clause is
+// implied by the other literals of the clause. Literals that are found to
+// be implied are marked as seen so they don't have to be checked again.
+func (s *Solver) redundant(p cnf.Lit, levels uint32) bool {
+	s.minStack = append(s.minStack[:0], p)
+	top := len(s.toClear)
+	for len(s.minStack) > 0 {
+		last := len(s.minStack) - 1
+		c := s.arena.lits(s.varinfo[s.minStack[last].Var()].reason)
+		s.minStack = s.minStack[:last]
+
+		// The first literal of a reason clause is the implied literal
+		for _, q := range c[1:] {
+			v := q.Var()
+			if s.seen[v] != 0 || s.level(v) == 0 {
+				continue
+			}
+
+			// If the literal is a decision or is at a level that isn't in
+			// the clause then it can't be implied by the clause. Undo the
+			// marks from this call since they may not be implied either.
+			if s.varinfo[v].reason == crefUndef || s.abstractLevel(v)&levels == 0 {
+				for _, l := range s.toClear[top:] {
+					s.seen[l.Var()] = 0
+				}
+				s.toClear = s.toClear[:top]
+				return false
+			}
+
+			s.seen[v] = 1
+			s.minStack = append(s.minStack, q)
+			s.toClear = append(s.toClear, q)
+		}
+	}
+
+	return true
+}
+
+// abstractLevel returns the level of the variable v as a bit in a 32-bit
+// mask. This is used to quickly check if a level might be in a set of
+// levels.
+func (s *Solver) abstractLevel(v int) uint32 {
+	return 1 << uint(s.level(v)&31)
+}
+
+// lbd computes the literal block distance of the given literals: the
+// number of distinct decision levels among them. Every literal must be
+// assigned.
+func (s *Solver) lbd(lits []cnf.Lit) int {
+	// Rather than clearing the level marks every time we just use a new
+	// stamp value for every call.
+	s.lbdStamp++
+	if need := s.decisionLevel() + 1; len(s.lbdLevels) < need {
+		s.lbdLevels = append(s.lbdLevels, make([]uint64, need-len(s.lbdLevels))...)
+	}
+
+	result := 0
+	for _, l := range lits {
+		level := s.level(l.Var())
+		if s.lbdLevels[level] != s.lbdStamp {
+			s.lbdLevels[level] = s.lbdStamp
+			result++
+		}
+	}
+
+	return result
+}
+
+// analyzeFinal determines the assumptions that are responsible for the
+// assumption p being false. This walks the trail backwards from the
+// assignment of p through the reason clauses. Every decision reached at
+// a level above zero is an assumption. The result is stored in s.failed.
+func (s *Solver) analyzeFinal(p cnf.Lit) {
+	s.failed = append(s.failed[:0], p)
+	if s.level(p.Var()) == 0 {
+		return
+	}
+
+	s.seen[p.Var()] = 1
+	for i := len(s.trail) - 1; i >= s.trailIdx[0]; i-- {
+		l := s.trail[i]
+		v := l.Var()
+		if s.seen[v] == 0 {
+			continue
+		}
+
+		if reason := s.varinfo[v].reason; reason == crefUndef {
+			// A decision: this is an assumption.
+			s.failed = append(s.failed, l)
+		} else {
+			for _, q := range s.arena.lits(reason) {
+				if q.Var() != v && s.level(q.Var()) > 0 {
+					s.seen[q.Var()] = 1
+				}
+			}
+		}
+
+		s.seen[v] = 0
+	}
+
+	s.seen[p.Var()] = 0
+}
@@ -0,0 +1,68 @@
+package sat
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+func TestSolverLearn_minimize(t *testing.T) {
+	cases := []struct {
+		Minimize bool
+		Result   []int
+		Removed  int64
+	}{
+		{false, []int{-3, -2, -1}, 0},
+		{true, []int{-3, -1}, 1},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d-%v", i, tc.Minimize), func(t *testing.T) {
+			s := New()
+			s.ClauseMinimization = tc.Minimize
+			s.AddFormula(cnf.NewFormulaFromInts([][]int{
+				[]int{-1, 2},
+				[]int{-3, -2, 4},
+				[]int{-3, -1, -4},
+			}))
+
+			// Deciding 1 implies 2. Deciding 3 then implies 4 which
+			// conflicts with 1. The first UIP clause contains -2 which is
+			// redundant since 2 is implied by 1.
+			s.newDecisionLevel()
+			s.assertLiteral(cnf.NewLitInt(1), crefUndef)
+			if c := s.propagate(); c != crefUndef {
+				t.Fatalf("conflict: %d", c)
+			}
+			s.newDecisionLevel()
+			s.assertLiteral(cnf.NewLitInt(3), crefUndef)
+			c := s.propagate()
+			if c == crefUndef {
+				t.Fatal("expected conflict")
+			}
+
+			if level := s.learn(c); level != 1 {
+				t.Fatalf("bad level: %d", level)
+			}
+
+			actual := cnf.Clause(s.learned).Int()
+			sort.Ints(actual)
+			if !reflect.DeepEqual(actual, tc.Result) {
+				t.Fatalf("bad: %#v", actual)
+			}
+			if n := s.Stats().MinimizedLiterals; n != tc.Removed {
+				t.Fatalf("bad: %d", n)
+			}
+
+			// Every seen mark should be cleared
+			for v, seen := range s.seen {
+				if seen != 0 {
+					t.Fatalf("var %d still seen", v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package sat
+
+import (
+	"bufio"
+	"strconv"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+// This file contains the DRAT proof output for the solver.
+
+// ProofFormat is the format of the DRAT proof written to Solver.Proof.
+type ProofFormat byte
+
+const (
+	// ProofText writes the proof in the textual DRAT format. Every line is
+	// a clause in DIMACS form, prefixed with "d " for deletions.
+	ProofText ProofFormat = iota
+
+	// ProofBinary writes the proof in the binary DRAT format. This is
+	// much more compact than the text format.
+	ProofBinary
+)
+
+// proofWriter writes DRAT proof steps to a buffered writer.
+type proofWriter struct {
+	w      *bufio.Writer
+	binary bool
+	buf    []byte
+	err    error
+}
+
+// proofAdd writes the addition of the clause made of lits to the proof,
+// if a proof is being written.
+func (s *Solver) proofAdd(lits []cnf.Lit) {
+	if p := s.proofOutput(); p != nil {
+		p.write('a', lits)
+	}
+}
+
+// proofDelete writes the deletion of the clause made of lits to the proof,
+// if a proof is being written.
+func (s *Solver) proofDelete(lits []cnf.Lit) {
+	if p := s.proofOutput(); p != nil {
+		p.write('d', lits)
+	}
+}
+
+// proofFlush flushes any buffered proof steps to Proof.
+func (s *Solver) proofFlush() {
+	if s.proof != nil && s.proof.err == nil {
+		s.proof.err = s.proof.w.Flush()
+	}
+}
+
+// ProofError returns the first error that occurred writing the proof to
+// Proof. Once an error occurs, no further proof steps are written.
+func (s *Solver) ProofError() error {
+	if s.proof == nil {
+		return nil
+	}
+
+	return s.proof.err
+}
+
+// proofOutput returns the writer for the proof or nil if no proof is
+// being written. The writer is initialized on first use.
+func (s *Solver) proofOutput() *proofWriter {
+	if s.Proof == nil {
+		return nil
+	}
+
+	if s.proof == nil {
+		s.proof = &proofWriter{
+			w:      bufio.NewWriter(s.Proof),
+			binary: s.ProofFormat == ProofBinary,
+		}
+	}
+
+	return s.proof
+}
+
+// write writes a single proof step. kind is 'a' for an addition and 'd'
+// for a deletion.
+func (p *proofWriter) write(kind byte, lits []cnf.Lit) {
+	if p.err != nil {
+		return
+	}
+
+	buf := p.buf[:0]
+	if p.binary {
+		// Binary DRAT encodes every literal as 2*var+sign which is exactly
+		// our representation of a literal. Each is written as a variable
+		// length unsigned integer and the clause ends with a zero.
+		buf = append(buf, kind)
+		for _, l := range lits {
+			for u := uint(l); ; u >>= 7 {
+				if u < 0x80 {
+					buf = append(buf, byte(u))
+					break
+				}
+
+				buf = append(buf, byte(u&0x7f|0x80))
+			}
+		}
+		buf = append(buf, 0)
+	} else {
+		if kind == 'd' {
+			buf = append(buf, "d "...)
+		}
+		for _, l := range lits {
+			buf = strconv.AppendInt(buf, int64(l.Int()), 10)
+			buf = append(buf, ' ')
+		}
+		buf = append(buf, "0\n"...)
+	}
+
+	_, p.err = p.w.Write(buf)
+	p.buf = buf
+}
@@ -0,0 +1,180 @@
+package sat
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/go-sat/cnf"
+	"github.com/mitchellh/go-sat/proof"
+)
+
+func TestProofWriter(t *testing.T) {
+	cases := []struct {
+		Name   string
+		Binary bool
+		Kind   byte
+		Lits   []int
+		Result []byte
+	}{
+		{
+			"text add",
+			false,
+			'a',
+			[]int{63, -8193},
+			[]byte("63 -8193 0\n"),
+		},
+
+		{
+			"text delete",
+			false,
+			'd',
+			[]int{-63, -8193},
+			[]byte("d -63 -8193 0\n"),
+		},
+
+		{
+			"text empty",
+			false,
+			'a',
+			nil,
+			[]byte("0\n"),
+		},
+
+		{
+			"binary add",
+			true,
+			'a',
+			[]int{63, -8193},
+			[]byte{0x61, 0x7e, 0x83, 0x80, 0x01, 0x00},
+		},
+
+		{
+			"binary delete",
+			true,
+			'd',
+			[]int{-63, -8193},
+			[]byte{0x64, 0x7f, 0x83, 0x80, 0x01, 0x00},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d-%s", i, tc.Name), func(t *testing.T) {
+			var buf bytes.Buffer
+			p := &proofWriter{w: bufio.NewWriter(&buf), binary: tc.Binary}
+			p.write(tc.Kind, cnf.NewClauseFromInts(tc.Lits))
+			p.w.Flush()
+
+			if !reflect.DeepEqual(buf.Bytes(), tc.Result) {
+				t.Fatalf("bad: %q", buf.Bytes())
+			}
+		})
+	}
+}
+
+func TestSolverProof(t *testing.T) {
+	var buf bytes.Buffer
+	s := New()
+	s.Proof = &buf
+	s.AddFormula(cnf.NewFormulaFromInts(pigeonhole(3)))
+	if s.Solve() {
+		t.Fatal("should be unsatisfiable")
+	}
+	if err := s.ProofError(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The proof must contain every learned clause and end in the empty clause
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if int64(len(lines)) != s.Stats().LearnedClauses+1 {
+		t.Fatalf("bad: %d lines\n\n%s", len(lines), buf.String())
+	}
+	if lines[len(lines)-1] != "0" {
+		t.Fatalf("bad: %s", buf.String())
+	}
+}
+
+func TestSolverProof_binary(t *testing.T) {
+	var buf bytes.Buffer
+	s := New()
+	s.Proof = &buf
+	s.ProofFormat = ProofBinary
+	s.AddFormula(cnf.NewFormulaFromInts(pigeonhole(3)))
+	if s.Solve() {
+		t.Fatal("should be unsatisfiable")
+	}
+
+	if b := buf.Bytes(); len(b) < 2 || b[0] != 'a' || !bytes.HasSuffix(b, []byte{0, 'a', 0}) {
+		t.Fatalf("bad: %q", b)
+	}
+}
+
+func TestSolverProof_addClause(t *testing.T) {
+	var buf bytes.Buffer
+	s := New()
+	s.Proof = &buf
+	s.AddClause(cnf.NewClauseFromInts([]int{1}))
+	s.AddClause(cnf.NewClauseFromInts([]int{-1}))
+	if s.Solve() {
+		t.Fatal("should be unsatisfiable")
+	}
+
+	if buf.String() != "0\n" {
+		t.Fatalf("bad: %q", buf.String())
+	}
+}
+
+// Test that the proofs for every unsatisfiable SATLIB problem are valid.
+func TestSolverProof_satlib(t *testing.T) {
+	for _, dir := range satlibDirs(t) {
+		base := filepath.Base(dir)
+		if strings.HasPrefix(base, "sat-") {
+			continue
+		}
+
+		entries, err := filepath.Glob(filepath.Join(dir, "*.cnf"))
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		count := 0
+		for _, path := range entries {
+			if strings.HasPrefix(base, "file-") && !strings.Contains(path, "no") {
+				continue
+			}
+
+			// If we're not running all satlib tests, only run a few
+			count++
+			if !*flagSatlib && count > satlibThreshold {
+				break
+			}
+
+			for _, format := range []ProofFormat{ProofText, ProofBinary} {
+				name := fmt.Sprintf("%s/%s/%d", base, filepath.Base(path), format)
+				t.Run(name, func(t *testing.T) {
+					var buf bytes.Buffer
+					s := New()
+					s.Decision = DecisionVSIDS
+					s.Restart = &LubyRestart{Unit: 10}
+					s.Deletion = DeletionLBD
+					s.ClauseMinimization = true
+					s.Proof = &buf
+					s.ProofFormat = format
+					s.AddFormula(satlibParse(t, path).Formula)
+					if s.Solve() {
+						t.Fatal("expected false, got true")
+					}
+
+					err := proof.VerifyDRAT(satlibParse(t, path).Formula, &buf)
+					if err != nil {
+						t.Fatalf("err: %s", err)
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package sat
+
+import (
+	"sort"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+// This file contains the learned clause database reduction for the solver.
+
+// DeletionPolicy is the policy used to remove learned clauses so that the
+// learned clause database doesn't grow without bound.
+type DeletionPolicy byte
+
+const (
+	// DeletionNone keeps every learned clause forever.
+	DeletionNone DeletionPolicy = iota
+
+	// DeletionLBD periodically removes half of the learned clauses,
+	// preferring to remove the clauses with the highest LBD (literal block
+	// distance). Clauses with an LBD of 2 or less ("glue clauses") are
+	// never removed. This is the strategy used by Glucose.
+	DeletionLBD
+)
+
+const (
+	// reduceFirst is the number of conflicts before the first reduction.
+	reduceFirst = 2000
+
+	// reduceInc is the amount the number of conflicts between reductions
+	// grows by after every reduction.
+	reduceInc = 300
+
+	// reduceKeepLBD is the LBD at or under which a learned clause is
+	// never removed.
+	reduceKeepLBD = 2
+)
+
+// learnedClause is a clause that was learned during conflict analysis.
+type learnedClause struct {
+	ref cref
+	lbd int
+}
+
+// addLearned adds a learned clause made up of lits with the given LBD to
+// the clause database and returns the clause. The clause must have at
+// least two literals.
+func (s *Solver) addLearned(lits []cnf.Lit, lbd int) cref {
+	c := s.arena.alloc(lits, true)
+	s.learnedClauses = append(s.learnedClauses, learnedClause{
+		ref: c,
+		lbd: lbd,
+	})
+	s.watchClause(c)
+	return c
+}
+
+// shouldReduce returns true if the learned clause database should be
+// reduced now. This should be called after every conflict.
+func (s *Solver) shouldReduce() bool {
+	if s.Deletion == DeletionNone {
+		return false
+	}
+
+	s.reduceConflicts++
+	if s.reduceConflicts < reduceFirst+reduceInc*s.reduceCount {
+		return false
+	}
+
+	s.reduceConflicts = 0
+	s.reduceCount++
+	return true
+}
+
+// reduceDB removes roughly half of the learned clauses, preferring clauses
+// with a high LBD. Clauses that are currently the reason for an assignment
+// are never removed.
+func (s *Solver) reduceDB() {
+	// Sort so that the worst clauses are first. The sort is stable so that
+	// the older clauses are removed first among clauses with equal LBD.
+	learned := s.learnedClauses
+	sort.SliceStable(learned, func(i, j int) bool {
+		return learned[i].lbd > learned[j].lbd
+	})
+
+	limit := len(learned) / 2
+	removed := 0
+	j := 0
+	for _, lc := range learned {
+		if removed < limit && lc.lbd > reduceKeepLBD && !s.locked(lc.ref) {
+			s.proofDelete(s.arena.lits(lc.ref))
+			s.arena.free(lc.ref)
+			removed++
+			continue
+		}
+
+		learned[j] = lc
+		j++
+	}
+
+	s.learnedClauses = learned[:j]
+	s.stats.DeletedClauses += int64(removed)
+
+	if s.Trace {
+		s.Tracer.Printf(
+			"[TRACE] sat: reduced learned clauses: removed %d, kept %d",
+			removed, j)
+	}
+
+	// Remove the watches for the deleted clauses all at once rather than
+	// searching the watch lists for every clause.
+	if removed > 0 {
+		s.cleanWatches()
+	}
+
+	// Reclaim the memory of the deleted clauses once enough of the arena
+	// is garbage.
+	if s.arena.wasted > len(s.arena.data)/2 {
+		s.garbageCollect()
+	}
+}
+
+// locked returns true if the clause is the reason for a current assignment.
+// The implied literal of a reason clause is always the first literal.
+func (s *Solver) locked(c cref) bool {
+	first := s.arena.lits(c)[0]
+	if s.valueLit(first) != triTrue {
+		return false
+	}
+
+	return s.varinfo[first.Var()].reason == c
+}
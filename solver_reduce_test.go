@@ -0,0 +1,118 @@
+package sat
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+func TestSolverReduceDB(t *testing.T) {
+	s := New()
+	s.AddFormula(cnf.NewFormulaFromInts([][]int{
+		[]int{1, 2, 3, 4},
+		[]int{-1, -2, -3, -4},
+	}))
+
+	// Add learned clauses: a locked clause, a glue clause and two
+	// clauses that can be removed.
+	locked := s.addLearned(cnf.NewClauseFromInts([]int{1, 2, 3}), 5)
+	s.addLearned(cnf.NewClauseFromInts([]int{-1, 4}), 2)
+	bad1 := s.addLearned(cnf.NewClauseFromInts([]int{2, 3, 4}), 4)
+	bad2 := s.addLearned(cnf.NewClauseFromInts([]int{-2, -3, 4}), 3)
+
+	// Make the locked clause the reason for an assignment
+	s.newDecisionLevel()
+	s.assertLiteral(cnf.NewLitInt(-2), crefUndef)
+	s.assertLiteral(cnf.NewLitInt(-3), crefUndef)
+	s.assertLiteral(cnf.NewLitInt(1), locked)
+
+	s.reduceDB()
+
+	// Half of the clauses are removed, the worst first
+	if len(s.learnedClauses) != 2 {
+		t.Fatalf("bad: %#v", s.learnedClauses)
+	}
+	for _, lc := range s.learnedClauses {
+		if lc.ref == bad1 || lc.ref == bad2 {
+			t.Fatalf("should be removed: %s", cnf.Clause(s.arena.lits(lc.ref)))
+		}
+	}
+	if !s.arena.deleted(bad1) || !s.arena.deleted(bad2) {
+		t.Fatal("clauses should be deleted")
+	}
+
+	// The removed clauses should no longer be watched
+	for _, ws := range s.watches {
+		for _, w := range ws {
+			if w.ref == bad1 || w.ref == bad2 {
+				t.Fatalf("bad clause still watched: %s", w)
+			}
+		}
+	}
+}
+
+func TestSolverGarbageCollect(t *testing.T) {
+	s := New()
+	s.AddFormula(cnf.NewFormulaFromInts([][]int{
+		[]int{1, 2, 3},
+		[]int{-1, -2},
+	}))
+	s.growVars(4)
+
+	dead := s.addLearned(cnf.NewClauseFromInts([]int{1, 3, 4}), 3)
+	kept := s.addLearned(cnf.NewClauseFromInts([]int{-1, 3, 4}), 3)
+
+	// Make the kept clause a reason so that reasons are relocated
+	s.newDecisionLevel()
+	s.assertLiteral(cnf.NewLitInt(1), crefUndef)
+	s.assertLiteral(cnf.NewLitInt(-4), crefUndef)
+	s.assertLiteral(cnf.NewLitInt(3), kept)
+
+	s.arena.free(dead)
+	s.learnedClauses = s.learnedClauses[1:]
+	s.cleanWatches()
+	size := len(s.arena.data)
+	s.garbageCollect()
+
+	if len(s.arena.data) != size-5 || s.arena.wasted != 0 {
+		t.Fatalf("bad: %d %d", len(s.arena.data), s.arena.wasted)
+	}
+
+	// Every reference should be to an equal clause in the new arena
+	var actual [][]int
+	for _, c := range s.clauses {
+		actual = append(actual, cnf.Clause(s.arena.lits(c)).Int())
+	}
+	for _, lc := range s.learnedClauses {
+		actual = append(actual, cnf.Clause(s.arena.lits(lc.ref)).Int())
+	}
+	expected := [][]int{
+		[]int{1, 2, 3},
+		[]int{-1, -2},
+		[]int{-1, 3, 4},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	if r := s.varinfo[3].reason; r != s.learnedClauses[0].ref {
+		t.Fatalf("bad reason: %d", r)
+	}
+
+	watched := 0
+	for l, ws := range s.watches {
+		for _, w := range ws {
+			// The first two literals of a clause are watched
+			lits := s.arena.lits(w.ref)
+			if neg := cnf.Lit(l).Neg(); lits[0] != neg && lits[1] != neg {
+				t.Fatalf("bad watch for %s: %s", cnf.Lit(l), w)
+			}
+
+			watched++
+		}
+	}
+	if watched != 6 {
+		t.Fatalf("bad: %d", watched)
+	}
+}
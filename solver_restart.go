@@ -0,0 +1,129 @@
+package sat
+
+// This file contains the restart policies for the solver.
+
+// RestartPolicy decides when the solver should restart its search.
+//
+// A restart backtracks to decision level 0 but keeps every learned clause,
+// so no progress is lost. Restarts help the solver recover from bad early
+// decisions, which is especially important for heavy-tailed problems.
+//
+// Restart policies are stateful and must not be shared between solvers.
+type RestartPolicy interface {
+	// Conflict is called after every conflict with the LBD (literal block
+	// distance, the number of distinct decision levels) of the clause that
+	// was learned from the conflict. It returns true if the solver should
+	// restart.
+	Conflict(lbd int) bool
+}
+
+// LubyRestart restarts after a number of conflicts following the Luby
+// sequence (1, 1, 2, 1, 1, 2, 4, 1, ...) multiplied by Unit.
+type LubyRestart struct {
+	// Unit is the number of conflicts the sequence is multiplied by.
+	// This defaults to 100 if it is zero.
+	Unit int
+
+	idx       int // index into the luby sequence
+	conflicts int // conflicts since the last restart
+}
+
+// Conflict implements RestartPolicy.
+func (r *LubyRestart) Conflict(lbd int) bool {
+	unit := r.Unit
+	if unit <= 0 {
+		unit = 100
+	}
+
+	r.conflicts++
+	if r.conflicts < unit*luby(r.idx) {
+		return false
+	}
+
+	r.idx++
+	r.conflicts = 0
+	return true
+}
+
+// GlucoseRestart restarts dynamically based on the quality of recently
+// learned clauses, as done by Glucose. If the average LBD of the recent
+// learned clauses is sufficiently worse than the average LBD over the
+// entire search, the solver restarts.
+type GlucoseRestart struct {
+	// Window is the number of recent conflicts that make up the short
+	// term average. This defaults to 50 if it is zero.
+	Window int
+
+	// K is the factor the short term average is multiplied by before it
+	// is compared to the long term average. A restart happens if
+	// K * short > long. This defaults to 0.8 if it is zero.
+	K float64
+
+	queue    []int // LBD of recent conflicts (ring buffer)
+	queueIdx int   // next index to write in queue
+	queueSum int   // sum of the values in queue
+
+	conflicts int // total conflicts
+	sum       int // total sum of LBD
+}
+
+// Conflict implements RestartPolicy.
+func (r *GlucoseRestart) Conflict(lbd int) bool {
+	window := r.Window
+	if window <= 0 {
+		window = 50
+	}
+	k := r.K
+	if k <= 0 {
+		k = 0.8
+	}
+
+	r.conflicts++
+	r.sum += lbd
+
+	// Add the LBD to the queue, removing the oldest if it is full
+	if len(r.queue) < window {
+		r.queue = append(r.queue, lbd)
+	} else {
+		r.queueSum -= r.queue[r.queueIdx]
+		r.queue[r.queueIdx] = lbd
+		r.queueIdx = (r.queueIdx + 1) % window
+	}
+	r.queueSum += lbd
+
+	// We need a full queue before we have enough data to restart
+	if len(r.queue) < window {
+		return false
+	}
+
+	short := float64(r.queueSum) / float64(len(r.queue))
+	long := float64(r.sum) / float64(r.conflicts)
+	if short*k <= long {
+		return false
+	}
+
+	// Clear the queue so that the next restart requires a full window
+	r.queue = r.queue[:0]
+	r.queueIdx = 0
+	r.queueSum = 0
+	return true
+}
+
+// luby returns the value at index i (starting at zero) of the Luby sequence.
+func luby(i int) int {
+	// Find the finite subsequence that contains index i and its size
+	size, seq := 1, 0
+	for size < i+1 {
+		seq++
+		size = 2*size + 1
+	}
+
+	// Narrow down until i is the last index of a subsequence
+	for size-1 != i {
+		size = (size - 1) >> 1
+		seq--
+		i = i % size
+	}
+
+	return 1 << uint(seq)
+}
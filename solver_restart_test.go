@@ -0,0 +1,64 @@
+package sat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLuby(t *testing.T) {
+	expected := []int{1, 1, 2, 1, 1, 2, 4, 1, 1, 2, 1, 1, 2, 4, 8, 1}
+
+	var result []int
+	for i := range expected {
+		result = append(result, luby(i))
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestLubyRestart(t *testing.T) {
+	r := &LubyRestart{Unit: 2}
+
+	// Record the conflicts at which a restart happens
+	var result []int
+	for i := 1; i <= 16; i++ {
+		if r.Conflict(1) {
+			result = append(result, i)
+		}
+	}
+
+	expected := []int{2, 4, 8, 10, 12, 16}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
+func TestGlucoseRestart(t *testing.T) {
+	r := &GlucoseRestart{Window: 3}
+
+	// Good clauses should never cause a restart
+	for i := 0; i < 10; i++ {
+		if r.Conflict(2) {
+			t.Fatalf("restart on conflict %d", i)
+		}
+	}
+
+	// Getting much worse should restart once the window is affected enough
+	restarted := false
+	for i := 0; i < 3; i++ {
+		if r.Conflict(10) {
+			restarted = true
+			break
+		}
+	}
+	if !restarted {
+		t.Fatal("should restart")
+	}
+
+	// The window must fill again before another restart
+	if r.Conflict(10) {
+		t.Fatal("should not restart with a partial window")
+	}
+}
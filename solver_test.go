@@ -1,13 +1,17 @@
 package sat
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/mitchellh/go-sat/cnf"
 	"github.com/mitchellh/go-sat/dimacs"
@@ -93,8 +97,116 @@ func TestSolve_table(t *testing.T) {
 	}
 }
 
-// Test the solver with SATLIB problems.
+// Test the solver with SATLIB problems under a sweep of configurations.
 func TestSolver_satlib(t *testing.T) {
+	cases := []struct {
+		Name      string
+		Configure func(*Solver)
+	}{
+		{
+			"default",
+			nil,
+		},
+
+		{
+			"vsids",
+			func(s *Solver) {
+				s.Decision = DecisionVSIDS
+			},
+		},
+
+		{
+			"luby",
+			func(s *Solver) {
+				s.Decision = DecisionVSIDS
+				s.Restart = &LubyRestart{}
+			},
+		},
+
+		{
+			"glucose restarts",
+			func(s *Solver) {
+				s.Decision = DecisionVSIDS
+				s.Restart = &GlucoseRestart{}
+			},
+		},
+
+		{
+			"deletion",
+			func(s *Solver) {
+				s.Decision = DecisionVSIDS
+				s.Restart = &GlucoseRestart{}
+				s.Deletion = DeletionLBD
+			},
+		},
+
+		{
+			"phase saving",
+			func(s *Solver) {
+				s.Decision = DecisionVSIDS
+				s.Restart = &LubyRestart{}
+				s.Polarity = PolarityNegative
+				s.PhaseSaving = true
+			},
+		},
+
+		{
+			"minimization",
+			func(s *Solver) {
+				s.Decision = DecisionVSIDS
+				s.Restart = &LubyRestart{}
+				s.ClauseMinimization = true
+			},
+		},
+
+		{
+			"glucose",
+			func(s *Solver) {
+				s.Options = Options{
+					Decision:           DecisionVSIDS,
+					Restart:            &GlucoseRestart{},
+					Deletion:           DeletionLBD,
+					PhaseSaving:        true,
+					ClauseMinimization: true,
+				}
+			},
+		},
+
+		{
+			"random polarity",
+			func(s *Solver) {
+				s.Options = Options{
+					Decision: DecisionVSIDS,
+					Restart:  &LubyRestart{},
+					Polarity: PolarityRandom,
+					Seed:     7,
+				}
+			},
+		},
+
+		{
+			"random decisions",
+			func(s *Solver) {
+				s.Options = Options{
+					Decision:           DecisionVSIDS,
+					Restart:            &LubyRestart{},
+					RandomDecisionFreq: 0.05,
+					Seed:               11,
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			satlibTest(t, tc.Configure)
+		})
+	}
+}
+
+// satlibTest runs the SATLIB problems. If configure is non-nil it is
+// called with every solver prior to adding the formula.
+func satlibTest(t *testing.T, configure func(*Solver)) {
 	// Get the dirs containing our tests, this will be sorted already
 	dirs := satlibDirs(t)
 
@@ -102,12 +214,12 @@ func TestSolver_satlib(t *testing.T) {
 	for _, d := range dirs {
 		// Run the tests for this dir
 		t.Run(filepath.Base(d), func(t *testing.T) {
-			satlibTestDir(t, d)
+			satlibTestDir(t, d, configure)
 		})
 	}
 }
 
-func satlibTestDir(t *testing.T, dir string) {
+func satlibTestDir(t *testing.T, dir string, configure func(*Solver)) {
 	base := filepath.Base(dir)
 
 	// If the directory has the prefix "sat" then we expect all
@@ -146,12 +258,12 @@ func satlibTestDir(t *testing.T, dir string) {
 
 		// Test this entry
 		t.Run(entry, func(t *testing.T) {
-			satlibTestFile(t, filepath.Join(dir, entry), fileSat)
+			satlibTestFile(t, filepath.Join(dir, entry), fileSat, configure)
 		})
 	}
 }
 
-func satlibTestFile(t *testing.T, path string, expected bool) {
+func satlibTestFile(t *testing.T, path string, expected bool, configure func(*Solver)) {
 	// Parse the problem
 	f, err := os.Open(path)
 	if err != nil {
@@ -168,6 +280,9 @@ func satlibTestFile(t *testing.T, path string, expected bool) {
 	s := New()
 	s.Trace = *flagImmediate
 	s.Tracer = newTracer(t)
+	if configure != nil {
+		configure(s)
+	}
 	s.AddFormula(p.Formula)
 
 	actual := s.Solve()
@@ -231,3 +346,629 @@ type immediateTracer struct{}
 func (t *immediateTracer) Printf(format string, v ...interface{}) {
 	fmt.Printf(format+"\n", v...)
 }
+
+func TestSolveWithAssumptions(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Formula     [][]int
+		Assumptions []int
+		Result      bool
+	}{
+		{
+			"no assumptions",
+			[][]int{
+				[]int{1, 2},
+				[]int{-1, 2},
+			},
+			nil,
+			true,
+		},
+
+		{
+			"satisfiable assumption",
+			[][]int{
+				[]int{1, 2},
+				[]int{-1, 3},
+			},
+			[]int{1},
+			true,
+		},
+
+		{
+			"unsatisfiable assumption",
+			[][]int{
+				[]int{1, 2},
+				[]int{-1, 2},
+			},
+			[]int{-2},
+			false,
+		},
+
+		{
+			"assumption false at level 0",
+			[][]int{
+				[]int{-3},
+				[]int{1, 2},
+			},
+			[]int{1, 3},
+			false,
+		},
+
+		{
+			"conflicting assumptions",
+			[][]int{
+				[]int{1, 2},
+			},
+			[]int{1, -1},
+			false,
+		},
+
+		{
+			"unsatisfiable via propagation",
+			[][]int{
+				[]int{-1, 2},
+				[]int{-2, 3},
+				[]int{-3, -4},
+				[]int{5, 6},
+			},
+			[]int{4, 1},
+			false,
+		},
+
+		{
+			"duplicate assumption",
+			[][]int{
+				[]int{1, 2},
+				[]int{-1, 3},
+			},
+			[]int{1, 3, 1},
+			true,
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d-%s", i, tc.Name), func(t *testing.T) {
+			s := New()
+			s.Trace = true
+			s.Tracer = newTracer(t)
+			s.AddFormula(cnf.NewFormulaFromInts(tc.Formula))
+
+			var assumptions []cnf.Lit
+			for _, v := range tc.Assumptions {
+				assumptions = append(assumptions, cnf.NewLitInt(v))
+			}
+
+			actual := s.SolveWithAssumptions(assumptions)
+			if actual != tc.Result {
+				t.Fatalf("bad: %#v", actual)
+			}
+
+			// The assumptions must hold in the solution
+			if actual {
+				solution := s.Assignments()
+				for _, l := range assumptions {
+					if solution[l.Var()] == l.Sign() {
+						t.Fatalf("assumption %s doesn't hold: %#v", l, solution)
+					}
+				}
+			}
+
+			// The assumptions are temporary so the formula itself should
+			// be satisfiable again.
+			if !s.Solve() {
+				t.Fatal("should be satisfiable without assumptions")
+			}
+		})
+	}
+}
+
+func TestSolver_incremental(t *testing.T) {
+	s := New()
+	s.Trace = true
+	s.Tracer = newTracer(t)
+	s.AddFormula(cnf.NewFormulaFromInts([][]int{
+		[]int{1, 2, 3},
+		[]int{-1, -2},
+	}))
+
+	if !s.Solve() {
+		t.Fatal("should be satisfiable")
+	}
+
+	// Solving again should find a solution again
+	if !s.Solve() {
+		t.Fatal("should be satisfiable")
+	}
+
+	// Add more clauses between solves
+	s.AddClause(cnf.NewClauseFromInts([]int{-3}))
+	s.AddClause(cnf.NewClauseFromInts([]int{-1}))
+	if !s.Solve() {
+		t.Fatal("should be satisfiable")
+	}
+	if solution := s.Assignments(); !solution[2] || solution[1] || solution[3] {
+		t.Fatalf("bad: %#v", solution)
+	}
+
+	if s.SolveWithAssumptions([]cnf.Lit{cnf.NewLitInt(-2)}) {
+		t.Fatal("should be unsatisfiable under assumptions")
+	}
+
+	// Make it permanently unsatisfiable
+	s.AddClause(cnf.NewClauseFromInts([]int{-2}))
+	if s.Solve() {
+		t.Fatal("should be unsatisfiable")
+	}
+	if s.SolveWithAssumptions([]cnf.Lit{cnf.NewLitInt(1)}) {
+		t.Fatal("should be unsatisfiable")
+	}
+}
+
+// Test solving SATLIB problems under assumptions. Every result must
+// agree with a fresh solver that has the assumptions added as clauses.
+func TestSolver_satlibAssumptions(t *testing.T) {
+	dir := filepath.Join("testdata", "satlib", "sat-uniform-20-91")
+	entries, err := filepath.Glob(filepath.Join(dir, "*.cnf"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !*flagSatlib && len(entries) > satlibThreshold {
+		entries = entries[:satlibThreshold]
+	}
+
+	for _, path := range entries {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			p := satlibParse(t, path)
+
+			s := New()
+			s.Decision = DecisionVSIDS
+			s.AddFormula(p.Formula)
+			if !s.Solve() {
+				t.Fatal("should be satisfiable")
+			}
+
+			// Flip pairs of variables from the first solution
+			solution := s.Assignments()
+			for v := 1; v < p.Variables; v++ {
+				assumptions := []cnf.Lit{
+					cnf.NewLit(v, solution[v]),
+					cnf.NewLit(v+1, solution[v+1]),
+				}
+
+				expected := New()
+				expected.AddFormula(satlibParse(t, path).Formula)
+				for _, l := range assumptions {
+					expected.AddClause(cnf.Clause{l})
+				}
+
+				actual := s.SolveWithAssumptions(assumptions)
+				if actual != expected.Solve() {
+					t.Fatalf("%s: expected %v, got %v", assumptions, !actual, actual)
+				}
+				if actual {
+					continue
+				}
+
+				// The failed assumptions alone must be unsatisfiable
+				failed := New()
+				failed.AddFormula(satlibParse(t, path).Formula)
+				for _, l := range s.FailedAssumptions() {
+					failed.AddClause(cnf.Clause{l})
+				}
+				if failed.Solve() {
+					t.Fatalf("%s: failed assumptions are satisfiable: %s",
+						assumptions, s.FailedAssumptions())
+				}
+			}
+		})
+	}
+}
+
+func satlibParse(t *testing.T, path string) *dimacs.Problem {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+
+	p, err := dimacs.Parse(f)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return p
+}
+
+func TestSolverFailedAssumptions(t *testing.T) {
+	cases := []struct {
+		Name        string
+		Formula     [][]int
+		Assumptions []int
+		Failed      []int
+	}{
+		{
+			"unsatisfiable regardless",
+			[][]int{
+				[]int{1},
+				[]int{-1},
+			},
+			[]int{2},
+			[]int{},
+		},
+
+		{
+			"assumption false at level 0",
+			[][]int{
+				[]int{-3},
+				[]int{1, 2},
+			},
+			[]int{1, 3},
+			[]int{3},
+		},
+
+		{
+			"conflicting assumptions",
+			[][]int{
+				[]int{1, 2},
+			},
+			[]int{4, 1, 5, -1},
+			[]int{-1, 1},
+		},
+
+		{
+			"through propagation",
+			[][]int{
+				[]int{-1, 2},
+				[]int{-2, 3},
+				[]int{-3, -4},
+				[]int{5, 6},
+			},
+			[]int{5, 4, 6, 1},
+			[]int{1, 4},
+		},
+
+		{
+			"through learning",
+			[][]int{
+				[]int{-1, 2, 3},
+				[]int{-1, 2, -3},
+				[]int{-1, -2, 3},
+				[]int{-1, -2, -3},
+			},
+			[]int{4, 1},
+			[]int{1},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d-%s", i, tc.Name), func(t *testing.T) {
+			s := New()
+			s.Trace = true
+			s.Tracer = newTracer(t)
+			s.AddFormula(cnf.NewFormulaFromInts(tc.Formula))
+
+			var assumptions []cnf.Lit
+			for _, v := range tc.Assumptions {
+				assumptions = append(assumptions, cnf.NewLitInt(v))
+			}
+
+			if s.SolveWithAssumptions(assumptions) {
+				t.Fatal("should be unsatisfiable")
+			}
+
+			actual := cnf.Clause(s.FailedAssumptions()).Int()
+			sort.Ints(actual)
+			if !reflect.DeepEqual(actual, tc.Failed) {
+				t.Fatalf("bad: %#v", actual)
+			}
+		})
+	}
+}
+
+func TestSolverFailedAssumptions_unsatAfterSolve(t *testing.T) {
+	s := New()
+	s.AddFormula(cnf.NewFormulaFromInts([][]int{[]int{1, 2}}))
+
+	assumptions := []cnf.Lit{cnf.NewLitInt(1), cnf.NewLitInt(-1)}
+	if s.SolveWithAssumptions(assumptions) {
+		t.Fatal("should be unsatisfiable")
+	}
+	if len(s.FailedAssumptions()) == 0 {
+		t.Fatal("should have failed assumptions")
+	}
+
+	// Making the formula unsatisfiable clears the failed assumptions
+	s.AddClause(cnf.Clause{cnf.NewLitInt(1)})
+	s.AddClause(cnf.Clause{cnf.NewLitInt(-1)})
+	if actual := s.FailedAssumptions(); len(actual) != 0 {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	// Solving again with the same assumptions doesn't return them
+	if s.SolveWithAssumptions(assumptions) {
+		t.Fatal("should be unsatisfiable")
+	}
+	if actual := s.FailedAssumptions(); len(actual) != 0 {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestSolveContext_cancel(t *testing.T) {
+	s := New()
+	s.AddFormula(cnf.NewFormulaFromInts(pigeonhole(8)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if r := s.SolveContext(ctx); r != ResultUnknown {
+		t.Fatalf("bad: %s", r)
+	}
+	if s.decisionLevel() != 0 {
+		t.Fatalf("bad: %d", s.decisionLevel())
+	}
+}
+
+func TestSolveContext_deadline(t *testing.T) {
+	s := New()
+	s.AddFormula(cnf.NewFormulaFromInts(pigeonhole(12)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if r := s.SolveContext(ctx); r != ResultUnknown {
+		t.Fatalf("bad: %s", r)
+	}
+	if s.decisionLevel() != 0 {
+		t.Fatalf("bad: %d", s.decisionLevel())
+	}
+}
+
+func TestSolveContext_resume(t *testing.T) {
+	s := New()
+	s.Decision = DecisionVSIDS
+	s.AddFormula(cnf.NewFormulaFromInts(pigeonhole(5)))
+
+	// Interrupt the solver a number of times before letting it finish
+	for i := 0; i < 5; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		r := s.SolveContext(ctx)
+		cancel()
+		if r == ResultSat {
+			t.Fatalf("bad: %s", r)
+		}
+	}
+
+	if r := s.SolveContext(context.Background()); r != ResultUnsat {
+		t.Fatalf("bad: %s", r)
+	}
+}
+
+// pigeonhole returns the formula that states n+1 pigeons fit into n holes
+// with at most one pigeon per hole. This is unsatisfiable and difficult
+// for CDCL solvers as n grows.
+func pigeonhole(n int) [][]int {
+	v := func(pigeon, hole int) int { return pigeon*n + hole + 1 }
+
+	var result [][]int
+
+	// Every pigeon is in a hole
+	for p := 0; p <= n; p++ {
+		var c []int
+		for h := 0; h < n; h++ {
+			c = append(c, v(p, h))
+		}
+
+		result = append(result, c)
+	}
+
+	// No two pigeons share a hole
+	for h := 0; h < n; h++ {
+		for p1 := 0; p1 <= n; p1++ {
+			for p2 := p1 + 1; p2 <= n; p2++ {
+				result = append(result, []int{-v(p1, h), -v(p2, h)})
+			}
+		}
+	}
+
+	return result
+}
+
+func TestSolverBudget(t *testing.T) {
+	cases := []struct {
+		Name      string
+		Configure func(*Solver)
+		Check     func(*Solver) bool
+	}{
+		{
+			"conflicts",
+			func(s *Solver) { s.ConflictBudget = 10 },
+			func(s *Solver) bool { return s.stats.Conflicts == 10 },
+		},
+
+		{
+			"decisions",
+			func(s *Solver) { s.DecisionBudget = 10 },
+			func(s *Solver) bool { return s.stats.Decisions == 10 },
+		},
+
+		{
+			"propagations",
+			func(s *Solver) { s.PropagationBudget = 10 },
+			func(s *Solver) bool { return s.stats.Propagations >= 10 },
+		},
+	}
+
+	for i, tc := range cases {
+		t.Run(fmt.Sprintf("%d-%s", i, tc.Name), func(t *testing.T) {
+			s := New()
+			s.Trace = true
+			s.Tracer = newTracer(t)
+			s.AddFormula(cnf.NewFormulaFromInts(pigeonhole(8)))
+			tc.Configure(s)
+
+			if r := s.SolveContext(context.Background()); r != ResultUnknown {
+				t.Fatalf("bad: %s", r)
+			}
+			if !tc.Check(s) {
+				t.Fatalf("bad: %#v", s.Stats())
+			}
+			if s.decisionLevel() != 0 {
+				t.Fatalf("bad: %d", s.decisionLevel())
+			}
+		})
+	}
+}
+
+func TestSolverBudget_resume(t *testing.T) {
+	s := New()
+	s.Decision = DecisionVSIDS
+	s.ConflictBudget = 10
+	s.AddFormula(cnf.NewFormulaFromInts(pigeonhole(5)))
+
+	// Every call gets a new budget so we should eventually finish
+	calls := 0
+	r := ResultUnknown
+	for r == ResultUnknown {
+		calls++
+		r = s.SolveContext(context.Background())
+		if c := s.Stats().Conflicts; c > int64(calls)*s.ConflictBudget {
+			t.Fatalf("budget exceeded: %d", c)
+		}
+	}
+
+	if r != ResultUnsat {
+		t.Fatalf("bad: %s", r)
+	}
+	if calls == 1 {
+		t.Fatal("should take more than one call")
+	}
+}
+
+func TestSolverNewVar(t *testing.T) {
+	s := New()
+	if v := s.NewVar(); v != 1 {
+		t.Fatalf("bad: %d", v)
+	}
+
+	// Adding a clause allocates every var up to the largest
+	s.AddClause(cnf.NewClauseFromInts([]int{2, -4}))
+	if n := s.NumVars(); n != 4 {
+		t.Fatalf("bad: %d", n)
+	}
+	if v := s.NewVar(); v != 5 {
+		t.Fatalf("bad: %d", v)
+	}
+
+	// Every allocated var is assigned in a solution, even if it isn't in
+	// any clause.
+	if !s.Solve() {
+		t.Fatal("expected true, got false")
+	}
+	if len(s.Assignments()) != 5 {
+		t.Fatalf("bad: %#v", s.Assignments())
+	}
+}
+
+// Test that solving is deterministic: the same problem with the same
+// options and seed always takes exactly the same path to the same result.
+func TestSolver_deterministic(t *testing.T) {
+	paths := []string{
+		filepath.Join("testdata", "satlib", "sat-flat125-301", "flat125-1.cnf"),
+		filepath.Join("testdata", "satlib", "unsat-uniform-50-218", "uuf50-01.cnf"),
+	}
+
+	cases := []struct {
+		Name    string
+		Options func() Options
+	}{
+		{
+			"default",
+			func() Options { return Options{} },
+		},
+
+		{
+			"random polarity",
+			func() Options {
+				return Options{
+					Decision:    DecisionVSIDS,
+					Restart:     &LubyRestart{Unit: 10},
+					Deletion:    DeletionLBD,
+					Polarity:    PolarityRandom,
+					PhaseSaving: true,
+					Seed:        5,
+				}
+			},
+		},
+
+		{
+			"random decisions",
+			func() Options {
+				return Options{
+					Decision:           DecisionVSIDS,
+					Restart:            &GlucoseRestart{},
+					ClauseMinimization: true,
+					RandomDecisionFreq: 0.1,
+					Seed:               9,
+				}
+			},
+		},
+	}
+
+	for _, path := range paths {
+		for _, tc := range cases {
+			t.Run(fmt.Sprintf("%s/%s", filepath.Base(path), tc.Name), func(t *testing.T) {
+				// Solve the problem a few times, capturing the entire trace
+				var traces []string
+				var results []Result
+				var models []map[int]bool
+				for i := 0; i < 3; i++ {
+					var buf strings.Builder
+					s := New()
+					s.Options = tc.Options()
+					s.Trace = true
+					s.Tracer = log.New(&buf, "", 0)
+					s.AddFormula(satlibParse(t, path).Formula)
+
+					results = append(results, s.SolveContext(context.Background()))
+					models = append(models, s.Assignments())
+					traces = append(traces, buf.String())
+				}
+
+				for i := 1; i < len(traces); i++ {
+					if results[i] != results[0] {
+						t.Fatalf("bad result %d: %s != %s", i, results[i], results[0])
+					}
+					if !reflect.DeepEqual(models[i], models[0]) {
+						t.Fatalf("bad model %d", i)
+					}
+					if traces[i] != traces[0] {
+						t.Fatalf("trace %d differs", i)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestSolver_deterministicSeed(t *testing.T) {
+	path := filepath.Join("testdata", "satlib", "sat-flat125-301", "flat125-1.cnf")
+
+	// Different seeds should take different paths
+	stats := make(map[Stats]struct{})
+	for seed := int64(1); seed <= 5; seed++ {
+		s := New()
+		s.Decision = DecisionVSIDS
+		s.Polarity = PolarityRandom
+		s.Seed = seed
+		s.AddFormula(satlibParse(t, path).Formula)
+		if !s.Solve() {
+			t.Fatal("expected true, got false")
+		}
+
+		stats[s.Stats()] = struct{}{}
+	}
+
+	if len(stats) == 1 {
+		t.Fatal("every seed took the same path")
+	}
+}
@@ -12,11 +12,15 @@ import (
 // Assignments returns the assigned variables and their value (true or false).
 // This is only valid if Solve returned true, in which case this is the
 // solution.
+//
+// Every variable from 1 to NumVars is included, even if it isn't in any
+// clause. Variables are allocated densely, so clauses over 1 and 5 also
+// allocate 2, 3 and 4, which are given an arbitrary value.
 func (s *Solver) Assignments() map[int]bool {
 	result := make(map[int]bool)
-	for k, v := range s.assigns {
-		if v != triUndef {
-			result[k] = v == triTrue
+	for v := 1; v <= s.numVars; v++ {
+		if value := s.assigns[v]; value != triUndef {
+			result[v] = value == triTrue
 		}
 	}
 
@@ -25,13 +29,13 @@ func (s *Solver) Assignments() map[int]bool {
 
 // ValueLit reads the currently set value for a literal.
 func (s *Solver) valueLit(l cnf.Lit) tribool {
-	result, ok := s.assigns[l.Var()]
-	if !ok || result == triUndef {
+	result := s.assigns[l.Var()]
+	if result == triUndef {
 		return triUndef
 	}
 
 	// If the literal is negative (signed), then XOR 1 will cause the bool
-	// to flip. If result is undef, this has no affect.
+	// to flip.
 	if l.Sign() {
 		result ^= 1
 	}
@@ -39,7 +43,7 @@ func (s *Solver) valueLit(l cnf.Lit) tribool {
 	return result
 }
 
-func (s *Solver) assertLiteral(l cnf.Lit, from cnf.Clause) {
+func (s *Solver) assertLiteral(l cnf.Lit, from cref) {
 	// Store the literal in the trail
 	v := l.Var()
 	s.assigns[v] = boolToTri(!l.Sign())
@@ -56,6 +60,9 @@ func (s *Solver) level(v int) int {
 // newDecisionLevel creates a new decision level within the trail
 func (s *Solver) newDecisionLevel() {
 	s.trailIdx = append(s.trailIdx, len(s.trail))
+	if level := s.decisionLevel(); level > s.stats.MaxDecisionLevel {
+		s.stats.MaxDecisionLevel = level
+	}
 }
 
 // decisionLevel returns the current decision level
@@ -72,9 +79,16 @@ func (s *Solver) trimToDecisionLevel(level int) {
 
 	lastIdx := s.trailIdx[level]
 
-	// Unassign anything in the trail in higher levels
+	// Unassign anything in the trail in higher levels. We save the phase
+	// of each variable here if phase saving is enabled.
 	for i := len(s.trail) - 1; i >= lastIdx; i-- {
-		delete(s.assigns, s.trail[i].Var())
+		l := s.trail[i]
+		v := l.Var()
+		if s.PhaseSaving {
+			s.phases[v] = s.assigns[v]
+		}
+		s.assigns[v] = triUndef
+		s.order.insert(v)
 	}
 
 	// Update our queue head
@@ -42,7 +42,8 @@ func TestSolverValueLit(t *testing.T) {
 	for i, tc := range cases {
 		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
 			s := New()
-			s.assertLiteral(cnf.NewLitInt(tc.Assert), nil)
+			s.growVars(4)
+			s.assertLiteral(cnf.NewLitInt(tc.Assert), crefUndef)
 
 			l := cnf.NewLitInt(tc.Lit)
 			result := s.valueLit(l)
@@ -81,12 +82,13 @@ func TestSolverTrimToDecisionLevel(t *testing.T) {
 	for i, tc := range cases {
 		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
 			s := New()
+			s.growVars(5)
 			for _, l := range tc.Assert {
 				if l < 0 {
 					s.newDecisionLevel()
 				}
 
-				s.assertLiteral(cnf.NewLitInt(l), nil)
+				s.assertLiteral(cnf.NewLitInt(l), crefUndef)
 			}
 
 			s.trimToDecisionLevel(tc.Level)
@@ -7,32 +7,58 @@ import (
 )
 
 // watchClause should be called for any new clause added to the formula.
-// This registers watches for the clause.
-func (s *Solver) watchClause(c cnf.Clause) {
-	c0 := c[0].Neg()
-	c1 := c[1].Neg()
+// This registers watches for the clause. The first two literals of the
+// clause are watched.
+func (s *Solver) watchClause(c cref) {
+	lits := s.arena.lits(c)
+	c0 := lits[0].Neg()
+	c1 := lits[1].Neg()
 
 	if s.Trace {
-		s.Tracer.Printf("[TRACE] sat: registering watchers for clause %s", c)
-		s.Tracer.Printf("[TRACE] sat: when %s, check %s", c0, c[1])
-		s.Tracer.Printf("[TRACE] sat: when %s, check %s", c1, c[0])
+		s.Tracer.Printf("[TRACE] sat: registering watchers for clause %s", cnf.Clause(lits))
+		s.Tracer.Printf("[TRACE] sat: when %s, check %s", c0, lits[1])
+		s.Tracer.Printf("[TRACE] sat: when %s, check %s", c1, lits[0])
 	}
 
-	s.watches[c0] = append(s.watches[c0], &watcher{
-		Clause: c,
-		Lit:    c[1],
+	// The blocker is initially the other watched literal. For binary
+	// clauses this never changes since it is the only other literal.
+	binary := len(lits) == 2
+	s.watches[c0] = append(s.watches[c0], watcher{
+		ref:     c,
+		blocker: lits[1],
+		binary:  binary,
 	})
-	s.watches[c1] = append(s.watches[c1], &watcher{
-		Clause: c,
-		Lit:    c[0],
+	s.watches[c1] = append(s.watches[c1], watcher{
+		ref:     c,
+		blocker: lits[0],
+		binary:  binary,
 	})
 }
 
+// cleanWatches removes the watches for every deleted clause.
+func (s *Solver) cleanWatches() {
+	for l, watches := range s.watches {
+		j := 0
+		for _, w := range watches {
+			if !s.arena.deleted(w.ref) {
+				watches[j] = w
+				j++
+			}
+		}
+
+		s.watches[l] = watches[:j]
+	}
+}
+
 // propagate performs unit propagation. This is made extremely efficient
 // due to the watched literal algorithm. The core idea of watched literals
 // is that a clause only needs to be checked for unit propagation if a
 // watched literal is modified.
-func (s *Solver) propagate() cnf.Clause {
+//
+// This returns the conflicting clause, or crefUndef if there is no
+// conflict. Propagation doesn't allocate memory other than to grow the
+// trail and watch lists.
+func (s *Solver) propagate() cref {
 	// qhead points to the first literal in the trail that we haven't
 	// yet checked. This allows literal assertions to occur and only the
 	// newly asserted literals (additions to the trail) need to be checked
@@ -41,11 +67,15 @@ func (s *Solver) propagate() cnf.Clause {
 		// Get the next literal assigned in the trail
 		p := s.trail[s.qhead]
 		s.qhead++
+		s.stats.Propagations++
 
 		if s.Trace {
 			s.Tracer.Printf("[TRACE] sat: looking for watches for: %s", p)
 		}
 
+		// We're going to need ~p for the remainder
+		pNeg := p.Neg()
+
 		// Get the list of watches associated with this literal. We
 		// maintain two indexes (i, j) because we'll be removing or
 		// modifying watches.
@@ -57,58 +87,74 @@ func (s *Solver) propagate() cnf.Clause {
 		// affected.
 	PROP_LOOP:
 		for i < len(watches) {
-			iW := watches[i]
-			if s.Trace {
-				s.Tracer.Printf("[TRACE] sat: watcher: %s", iW)
+			w := watches[i]
+			i++
+
+			// If the blocker is true, then this clause is already
+			// satisfied. We maintain the watch in the watch list and
+			// continue without ever looking at the clause.
+			blockerValue := s.valueLit(w.blocker)
+			if blockerValue == triTrue {
+				watches[j] = w
+				j++
+				continue
 			}
 
-			// If the value of the literal being watched is true, then
-			// this clause is already satisfied. We maintain the watch
-			// in the watch list and continue.
-			if s.valueLit(iW.Lit) == triTrue {
+			// The blocker of a binary clause is the other literal, so
+			// the clause is either unit or a conflict.
+			if w.binary {
+				watches[j] = w
+				j++
+
+				if blockerValue == triFalse {
+					if i != j {
+						j += copy(watches[j:], watches[i:])
+						s.watches[p] = watches[:j]
+					}
+
+					return w.ref
+				}
+
+				// Reason clauses have the implied literal first
+				lits := s.arena.lits(w.ref)
+				if lits[0] == pNeg {
+					lits[0], lits[1] = lits[1], pNeg
+				}
+
 				if s.Trace {
 					s.Tracer.Printf(
-						"[TRACE] sat: watched lit %s became true; clause is true: %s",
-						iW.Lit, iW.Clause)
+						"[TRACE] sat: asserting unit literal %s in binary clause %s",
+						w.blocker, cnf.Clause(lits))
 				}
 
-				watches[j] = watches[i]
-				j++
-				i++
+				s.assertLiteral(w.blocker, w.ref)
 				continue
 			}
 
-			// Increment
-			i++
-
-			// We're going to need ~p for the remainder
-			pNeg := p.Neg()
-
 			// We keep the false literal in c[1]
-			iLits := iW.Clause
-			first := iLits[0]
+			lits := s.arena.lits(w.ref)
+			first := lits[0]
 			if first == pNeg {
 				if s.Trace {
 					s.Tracer.Printf(
 						"[TRACE] sat: moving false literal %s to position 1",
-						iLits[0])
+						lits[0])
 				}
 
-				iLits[0], iLits[1] = iLits[1], pNeg
-				first = iLits[0]
+				lits[0], lits[1] = lits[1], pNeg
+				first = lits[0]
 			}
 
 			// newW is the new watcher that will replace the current
-			// watcher no matter what. We always want to watch the
-			// first literal.
-			newW := &watcher{Clause: iW.Clause, Lit: first}
+			// watcher no matter what. We always use the first literal as
+			// the blocker.
+			newW := watcher{ref: w.ref, blocker: first}
 
 			// c[1] always contains the negated literal (above), so we
 			// only have to check the first value to see if it is true.
-			// We have the first != lit check since that is significantly
-			// faster to fail than the ValueLit call (~1 or 2% faster) for
-			// a common case.
-			if first != iW.Lit && s.valueLit(first) == triTrue {
+			// We have the first != blocker check since that is faster to
+			// fail than the ValueLit call for a common case.
+			if first != w.blocker && s.valueLit(first) == triTrue {
 				watches[j] = newW
 				j++
 				continue
@@ -119,10 +165,10 @@ func (s *Solver) propagate() cnf.Clause {
 			// in the clause: if they're all false then we have a unit
 			// clause. If any are not false (true OR unassigned), then
 			// we watch that literal and move on.
-			for k := 2; k < len(iLits); k++ {
-				if s.valueLit(iLits[k]) != triFalse {
-					iLits[1], iLits[k] = iLits[k], pNeg
-					i1 := iLits[1].Neg()
+			for k := 2; k < len(lits); k++ {
+				if s.valueLit(lits[k]) != triFalse {
+					lits[1], lits[k] = lits[k], pNeg
+					i1 := lits[1].Neg()
 					s.watches[i1] = append(s.watches[i1], newW)
 					continue PROP_LOOP
 				}
@@ -142,33 +188,40 @@ func (s *Solver) propagate() cnf.Clause {
 					s.watches[p] = watches[:j]
 				}
 
-				return iW.Clause
+				return w.ref
 			}
 
 			if s.Trace {
 				s.Tracer.Printf(
 					"[TRACE] sat: asserting unit literal %s in clause %s",
-					first, iW.Clause)
+					first, cnf.Clause(lits))
 			}
 
 			// Assert the unit literal, caused by the clause it is part of
-			s.assertLiteral(first, iW.Clause)
+			s.assertLiteral(first, w.ref)
 		}
 
 		s.watches[p] = watches[:j]
 	}
 
 	// If we reached this point, we found no conflicts
-	return nil
+	return crefUndef
 }
 
 // watcher watches a single literal within a clause for the watched literal
-// algorithm.
+// algorithm. Watchers are stored by value in the watch lists.
 type watcher struct {
-	Clause cnf.Clause // Clause that this literal is part of
-	Lit    cnf.Lit    // Lit being watched
+	ref cref // clause that this literal is part of
+
+	// blocker is a literal of the clause. If it is true then the clause is
+	// satisfied and doesn't need to be looked at.
+	blocker cnf.Lit
+
+	// binary is true if the clause is binary. The blocker is then the
+	// other literal of the clause.
+	binary bool
 }
 
-func (w *watcher) String() string {
-	return fmt.Sprintf("watching lit %q in clause %s", w.Lit, w.Clause)
+func (w watcher) String() string {
+	return fmt.Sprintf("watching clause %d with blocker %s", w.ref, w.blocker)
 }
@@ -0,0 +1,43 @@
+package sat
+
+import (
+	"testing"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+func TestSolverPropagate_allocs(t *testing.T) {
+	// A chain of implications through binary and ternary clauses:
+	// x1 -> x2 -> ... -> x100 with x101 false.
+	ints := [][]int{[]int{-101}}
+	for v := 1; v < 100; v++ {
+		if v%2 == 0 {
+			ints = append(ints, []int{-v, v + 1})
+		} else {
+			ints = append(ints, []int{-v, v + 1, 101})
+		}
+	}
+
+	s := New()
+	s.AddFormula(cnf.NewFormulaFromInts(ints))
+
+	run := func() {
+		s.newDecisionLevel()
+		s.assertLiteral(cnf.NewLitInt(1), crefUndef)
+		if c := s.propagate(); c != crefUndef {
+			t.Fatalf("conflict: %d", c)
+		}
+		if len(s.trail) != 101 {
+			t.Fatalf("bad: %d", len(s.trail))
+		}
+
+		s.trimToDecisionLevel(0)
+	}
+
+	// Grow the trail and watch lists once
+	run()
+
+	if n := testing.AllocsPerRun(100, run); n != 0 {
+		t.Fatalf("bad: %v allocs", n)
+	}
+}
@@ -0,0 +1,25 @@
+package sat
+
+// Stats are statistics about the work done by a Solver. They are useful
+// for understanding how difficult a problem was for the solver and for
+// catching performance regressions.
+//
+// Every value is cumulative over every call to a Solve function.
+type Stats struct {
+	Decisions    int64 // decisions made (excluding assumptions)
+	Conflicts    int64 // conflicts found
+	Propagations int64 // literals propagated
+	Restarts     int64 // restarts performed
+
+	LearnedClauses    int64 // clauses learned, including unit clauses
+	LearnedLiterals   int64 // total literals in learned clauses
+	MinimizedLiterals int64 // literals removed from learned clauses by minimization
+	DeletedClauses    int64 // learned clauses removed from the database
+
+	MaxDecisionLevel int // highest decision level reached
+}
+
+// Stats returns the statistics for this solver.
+func (s *Solver) Stats() Stats {
+	return s.stats
+}
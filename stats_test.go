@@ -0,0 +1,59 @@
+package sat
+
+import (
+	"testing"
+
+	"github.com/mitchellh/go-sat/cnf"
+)
+
+func TestSolverStats(t *testing.T) {
+	s := New()
+	s.Decision = DecisionVSIDS
+	s.Restart = &LubyRestart{Unit: 1}
+	s.AddFormula(cnf.NewFormulaFromInts(pigeonhole(5)))
+	if s.Solve() {
+		t.Fatal("should be unsatisfiable")
+	}
+
+	stats := s.Stats()
+	if stats.Conflicts == 0 || stats.Decisions == 0 || stats.Propagations == 0 {
+		t.Fatalf("bad: %#v", stats)
+	}
+	if stats.Restarts == 0 {
+		t.Fatalf("bad: %#v", stats)
+	}
+	if stats.MaxDecisionLevel == 0 {
+		t.Fatalf("bad: %#v", stats)
+	}
+
+	// Every conflict except the final conflict learns a clause
+	if stats.LearnedClauses != stats.Conflicts-1 {
+		t.Fatalf("bad: %#v", stats)
+	}
+	if stats.LearnedLiterals < stats.LearnedClauses {
+		t.Fatalf("bad: %#v", stats)
+	}
+}
+
+func TestSolverStats_cumulative(t *testing.T) {
+	s := New()
+	s.AddFormula(cnf.NewFormulaFromInts([][]int{
+		[]int{1, 2},
+		[]int{-1, 2},
+	}))
+
+	if !s.Solve() {
+		t.Fatal("should be satisfiable")
+	}
+	first := s.Stats()
+	if first.Decisions == 0 {
+		t.Fatalf("bad: %#v", first)
+	}
+
+	if !s.Solve() {
+		t.Fatal("should be satisfiable")
+	}
+	if second := s.Stats(); second.Decisions <= first.Decisions {
+		t.Fatalf("bad: %#v", second)
+	}
+}